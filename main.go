@@ -6,40 +6,343 @@
 //
 // Usage:
 //
-//	hello-fresh-scrape [-l] [-o output] [-p page] [-y]
+//	hello-fresh-scrape [-all] [-l] [-o output] [-p page] [-y]
+//		[-cuisine cuisines] [-exclude-allergen allergens] [-f format]
+//		[-sort order] [-head n] [-cookbook file] [-sitemap url]
+//		[-dedup-name] [-available-in country] [-seen file]
+//		[-category categories] [-qr dir] [-compare url1,url2]
+//		[-archive file] [-lang languages] [-include-raw]
+//		[-min-priority priority] [-maxutensils n] [-search query]
+//		[-domain domain] [-manifest file] [-sort-ingredients]
+//		[-certify-free-of allergens] [-time-budget duration] [-no-spicy]
+//		[-timing] [-min-rating rating] [-schema] [-box-only]
+//		[-sum-nutrition] [-ids ids] [-normalize-units] [-retries n]
+//		[-allergen-report] [-chunk n] [-max-steps n] [-warn-pagination]
+//
+// The -all flag scrapes recipes from every available collection, skipping
+// pages that contain no recipes.
 //
 // The -l flag lists available collections to scrape recipes from.
+// With -f json, the collections are written as a JSON array of objects with
+// the LOC, LastMod, ChangeFreq, and Priority of each sitemap entry instead
+// of one URL per line.
 //
 // The -o flag specifies the name of a file to write instead of using standard output.
+// An s3://bucket/key URL uploads the output to that S3 object instead, using
+// credentials, region, and endpoint (for S3-compatible services) from the
+// standard AWS environment variables and configuration files.
 //
 // The -p flag specifies the URL of a page to scrape recipes from.
 //
-// The -y flag converts recipe IngredientYield IDs to names.
+// The -y flag converts recipe IngredientYield IDs to names. IDs missing from a
+// recipe's own ingredients are looked up among all scraped recipes.
+//
+// The -cuisine flag keeps only recipes of any of the comma-separated cuisines.
+// It may be repeated.
+//
+// The -exclude-allergen flag excludes recipes containing any of the
+// comma-separated allergens, checking both recipe-level and
+// ingredient-level allergen data. It may be repeated.
+//
+// The -f flag specifies the output format:
+//
+//   - json: the recipes as a JSON array (the default)
+//   - ndjson: one JSON recipe per line
+//   - checklist: a printable ingredient checklist grouped by recipe
+//   - csv: one CSV row of summary fields per recipe
+//   - html: a self-contained HTML gallery of the recipes
+//   - markdown: each recipe's name, headline, description, and ingredients
+//   - rss: an RSS 2.0 feed with one item per recipe
+//   - families: the distinct ingredient families used by the recipes as JSON
+//   - gofixture: Go source declaring the recipes for use as test fixtures
+//
+// Without -f, the format of recipe output is inferred from the extension of
+// the -o file: .json, .ndjson or .jsonl, .csv, .md or .markdown, .html or
+// .htm, .rss, or .go. An explicit -f overrides the inference, and it is an
+// error for the file to have another extension unless -f is given.
+//
+// The -sort flag sorts recipes before output. The order is one of name,
+// which sorts recipes alphabetically, ingredients, which sorts recipes by
+// number of ingredients, fewest first, or protein-ratio, which sorts recipes
+// by grams of protein per calorie, highest first, with recipes missing
+// either value last.
+//
+// The -head flag outputs only the first n recipes after filtering and sorting.
+//
+// The -cookbook flag writes the recipes to a PDF cookbook file with one recipe
+// per page, in addition to the regular output.
+//
+// The -sitemap flag specifies the URL of the recipe collections sitemap used to
+// list collections and validate pages, such as a country-specific sitemap or a
+// locally mirrored copy.
+//
+// The -dedup-name flag collapses recipes with the same name, such as a dish
+// published under different IDs across countries, keeping the one with the
+// most complete data.
+//
+// The -available-in flag keeps only recipes whose ingredients are all used in
+// the given country, such as US or DE, approximating availability.
+//
+// The -seen flag names a JSON file listing the IDs of previously seen recipes.
+// Only recipes not in the file are emitted, and their IDs are then added to it.
+//
+// The -category flag keeps only recipes in any of the comma-separated
+// categories. It may be repeated.
+//
+// The -qr flag writes a PNG QR code linking to each recipe into the directory
+// dir, named after the recipe slug. Recipes without a link are skipped.
+//
+// The -compare flag scrapes the recipes at two comma-separated URLs and prints
+// a side-by-side comparison of their times, calories, and ingredients instead
+// of the recipes themselves.
+//
+// The -archive flag writes the recipes, their images, and their recipe cards
+// to a tar file laid out as recipes.json, images/<slug>.jpg, and
+// cards/<slug>.pdf, in addition to the regular output.
+//
+// The -lang flag keeps only recipes written in any of the comma-separated
+// ISO 639-1 languages, such as en or de, as inferred from each recipe's
+// country. It may be repeated.
+//
+// The -include-raw flag wraps the JSON output in an object holding the recipes
+// and a Debug section with the raw data of each payload query, which helps
+// diagnose data that is not mapped to recipes.
+//
+// The -min-priority flag, used with -l, lists only collections whose sitemap
+// priority is at least the given value. Collections without a priority are
+// treated as having priority 0.
+//
+// The -maxutensils flag keeps only recipes requiring at most n utensils.
+//
+// The -search flag scrapes the recipes matching a search query instead of a
+// page. The -domain flag specifies the Hello Fresh website to search, such as
+// www.hellofresh.de; it defaults to www.hellofresh.com.
+//
+// The -manifest flag names a JSON file mapping recipe IDs to the UpdatedAt time
+// they were last emitted with. Only recipes that are new or have been updated
+// since are emitted, and their times are then recorded in the file.
+//
+// The -sort-ingredients flag sorts the ingredients of each recipe yield by
+// amount, largest first, comparing amounts in grams where possible.
+//
+// The -certify-free-of flag adds a FreeOf object to the JSON output mapping each
+// recipe ID to whether none of the comma-separated allergens appear in its
+// recipe-level or ingredient-level allergen data. It may be repeated and
+// requires -f json.
+//
+// The -time-budget flag selects recipes whose combined total time fits within
+// the given duration, such as 5h, preferring a variety of cuisines.
+//
+// The -no-spicy flag excludes recipes tagged as spicy.
+//
+// The -timing flag reports how long each page took to fetch and parse as a
+// table on standard error after the output is written.
+//
+// The -min-rating flag keeps only recipes with an average rating of at least
+// the given value.
+//
+// The -schema flag writes a JSON Schema describing the recipe JSON output
+// instead of scraping recipes.
+//
+// The -box-only flag keeps only recipes whose ingredients are all shipped in
+// the box, excluding recipes that need pantry items.
+//
+// The -sum-nutrition flag adds the nutrition of all emitted recipes, such
+// as the meals planned for a day, to the JSON output as TotalNutrition.
+// Entries are matched by name and unit; a nutrient reported in different
+// units is totalled separately. It requires -f json.
+//
+// The -ids flag scrapes the recipes with the given comma-separated IDs
+// from the website named by -domain instead of a page. IDs that cannot be
+// resolved to a recipe are reported and skipped.
+//
+// The -normalize-units flag rewrites ingredient and nutrition units in a
+// canonical form, so "gram", "grams", and "gr" all become "g".
+//
+// The -retries flag sets how many times a page is fetched again when its
+// response is cut off before the recipe data could be read; it defaults to
+// 2. Pages that are complete but contain no recipes are not retried.
+//
+// The -allergen-report flag prints a table of each allergen in the selected
+// recipes and the number of recipes containing it, most prevalent first,
+// instead of the recipes.
+//
+// The -chunk flag splits the recipes into files of n recipes each instead of
+// a single output. The files are named after the -o file with a number
+// before its extension, so -o output.json -chunk 100 writes output-001.json,
+// output-002.json, and so on.
+//
+// The -max-steps flag keeps only recipes with at most n cooking steps, as a
+// proxy for how involved they are. Recipes without step data count as
+// having no steps.
+//
+// The -warn-pagination flag logs a warning for each page that yields exactly
+// a common page size of recipes, such as 20 or 50, since such results are
+// often capped at the first page of a paginated listing.
 package main // import "github.com/matthewdargan/hello-fresh-scrape"
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/matthewdargan/hello-fresh-scrape/recipe"
 )
 
 const recipeHomePage = "https://www.hellofresh.com/recipes"
 
 var (
+	allFlag         = flag.Bool("all", false, "scrape recipes from all available collections")
+	allergenReport  = flag.Bool("allergen-report", false, "print how many recipes contain each allergen instead of the recipes")
+	headFlag        = flag.Int("head", 0, "output only the first `n` recipes")
+	schemaFlag      = flag.Bool("schema", false, "write a JSON Schema describing the recipe output and exit")
+	search          = flag.String("search", "", "scrape recipes matching the search `query`")
+	seenFile        = flag.String("seen", "", "emit only recipes not listed in the seen `file`, then add them to it")
+	sitemap         = flag.String("sitemap", recipe.SitemapURL, "`URL` of the recipe collections sitemap")
+	sortIngredients = flag.Bool("sort-ingredients", false, "sort yield ingredients by amount, largest first")
+	sumNutrition    = flag.Bool("sum-nutrition", false, "include the combined nutrition of the recipes in the JSON output")
+	sortFlag        = flag.String("sort", "", "sort recipes by `order` (name, ingredients, or protein-ratio)")
+	archive         = flag.String("archive", "", "write the recipes and their images and cards to a tar `file`")
+	availableIn     = flag.String("available-in", "", "keep only recipes whose ingredients are used in `country`")
+	boxOnly         = flag.Bool("box-only", false, "keep only recipes whose ingredients are all shipped in the box")
+	compareFlag     = flag.String("compare", "", "compare the recipes at the comma-separated `urls`")
+	chunk           = flag.Int("chunk", 0, "write the recipes to numbered files of `n` recipes each, named after the -o file")
+	cookbook        = flag.String("cookbook", "", "write a PDF cookbook of the recipes to `file`")
+	domain          = flag.String("domain", "www.hellofresh.com", "Hello Fresh website `domain` to search or scrape -ids from")
+	dedupName       = flag.Bool("dedup-name", false, "collapse recipes with the same name")
+	timing          = flag.Bool("timing", false, "report how long each page took to fetch and parse on standard error")
+	warnPagination  = flag.Bool("warn-pagination", false, "warn when a page yields exactly a common page size of recipes")
+	timeBudget      = flag.Duration("time-budget", 0, "select recipes whose combined total time fits within `duration`")
+	format          = flag.String("f", "json", "output `format` ("+strings.Join(recipe.Formats(), ", ")+")")
+	includeRaw      = flag.Bool("include-raw", false, "include the raw payload query data in the JSON output")
 	listFlag        = flag.Bool("l", false, "list available collections to scrape recipes from")
-	oFlag           = flag.String("o", "", "write output to `file` (default standard output)")
+	manifestFile    = flag.String("manifest", "", "emit only recipes updated since they were recorded in the manifest `file`, then record them")
+	maxSteps        = flag.Int("max-steps", -1, "keep only recipes with at most `n` cooking steps")
+	maxUtensils     = flag.Int("maxutensils", -1, "keep only recipes requiring at most `n` utensils")
+	minRating       = flag.Float64("min-rating", 0, "keep only recipes rated at least `rating`")
+	minPriority     = flag.Float64("min-priority", 0, "with -l, list only collections with at least `priority`")
+	normalizeUnits  = flag.Bool("normalize-units", false, "rewrite ingredient and nutrition units in a canonical form, such as g for grams")
+	noSpicy         = flag.Bool("no-spicy", false, "exclude spicy recipes")
+	oFlag           = flag.String("o", "", "write output to `file` or s3://bucket/key URL (default standard output)")
+	qrDir           = flag.String("qr", "", "write a PNG QR code of each recipe link to `dir`")
 	recipePage      = flag.String("p", "", "URL to scrape recipes from")
+	retries         = flag.Int("retries", 2, "refetch a page up to `n` times when its response is truncated")
 	yieldIDsToNames = flag.Bool("y", false, "convert recipe IngredientYield IDs to names")
 	output          *bufio.Writer
+	categories      stringSlice
+	certifyFreeOf   stringSlice
+	cuisines        stringSlice
+	excludeAllergen stringSlice
+	ids             idList
+	langs           stringSlice
 )
 
+// formatExtensions maps output file extensions to the output format
+// inferred from them when -f is not given.
+var formatExtensions = map[string]string{
+	".csv":      "csv",
+	".go":       "gofixture",
+	".htm":      "html",
+	".html":     "html",
+	".json":     "json",
+	".jsonl":    "ndjson",
+	".markdown": "markdown",
+	".md":       "markdown",
+	".ndjson":   "ndjson",
+	".rss":      "rss",
+}
+
+// listFlags are the flags that may be used with -l.
+var listFlags = map[string]bool{"f": true, "l": true, "min-priority": true, "o": true, "sitemap": true}
+
+func init() {
+	flag.Var(&categories, "category", "keep only recipes in any of the comma-separated `categories` (repeatable)")
+	flag.Var(&certifyFreeOf, "certify-free-of", "report whether each recipe is free of the comma-separated `allergens` (repeatable)")
+	flag.Var(&cuisines, "cuisine", "keep only recipes of the comma-separated `cuisines` (repeatable)")
+	flag.Var(&langs, "lang", "keep only recipes in any of the comma-separated `languages` (repeatable)")
+	flag.Var(&ids, "ids", "scrape the recipes with the comma-separated `ids` (repeatable)")
+	flag.Var(&excludeAllergen, "exclude-allergen", "exclude recipes containing any of the comma-separated `allergens` (repeatable)")
+}
+
+// A stringSlice is a repeatable flag.Value holding comma-separated
+// values. Values are lowercased and duplicates are dropped on insert.
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSlice) Set(v string) error {
+	for _, e := range strings.Split(v, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || s.contains(e) {
+			continue
+		}
+		*s = append(*s, e)
+	}
+	return nil
+}
+
+func (s stringSlice) contains(v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// An idList is a repeatable flag.Value holding comma-separated IDs.
+// Unlike stringSlice, it keeps the case of the IDs, since recipe IDs are
+// case-sensitive.
+type idList []string
+
+func (l *idList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *idList) Set(v string) error {
+	for _, e := range strings.Split(v, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" || stringSlice(*l).contains(e) {
+			continue
+		}
+		*l = append(*l, e)
+	}
+	return nil
+}
+
+// jsonOutput is the JSON output when -include-raw, -sum-nutrition, or
+// -certify-free-of is set.
+type jsonOutput struct {
+	Recipes        recipe.Recipes
+	TotalNutrition []recipe.Nutrition `json:",omitempty"`
+	FreeOf         map[string]bool    `json:",omitempty"`
+	Debug          *debugData         `json:",omitempty"`
+}
+
+type debugData struct {
+	Queries []json.RawMessage
+}
+
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: hello-fresh-scrape [-l] [-o output] [-p page] [-y]\n")
+	fmt.Fprintf(os.Stderr, "usage: hello-fresh-scrape [-all] [-l] [-o output] [-p page] [-y]\n\t[-cuisine cuisines] [-exclude-allergen allergens] [-f format]\n\t[-sort order] [-head n] [-cookbook file] [-sitemap url]\n\t[-dedup-name] [-available-in country] [-seen file]\n\t[-category categories] [-qr dir] [-compare url1,url2]\n\t[-archive file] [-lang languages] [-include-raw]\n\t[-min-priority priority] [-maxutensils n] [-search query]\n\t[-domain domain] [-manifest file] [-sort-ingredients]\n\t[-certify-free-of allergens] [-time-budget duration] [-no-spicy]\n\t[-timing] [-min-rating rating] [-schema] [-box-only]\n\t[-sum-nutrition] [-ids ids] [-normalize-units] [-retries n]\n\t[-allergen-report] [-chunk n] [-max-steps n] [-warn-pagination]\n")
 	flag.PrintDefaults()
 	os.Exit(2)
 }
@@ -49,58 +352,279 @@ func main() {
 	log.SetFlags(0)
 	flag.Usage = usage
 	flag.Parse()
-	if *listFlag && *recipePage != "" {
-		log.Fatal("cannot use -p with -l")
+	if *listFlag {
+		flag.Visit(func(f *flag.Flag) {
+			if !listFlags[f.Name] {
+				log.Fatalf("cannot use -%s with -l", f.Name)
+			}
+		})
+	}
+	if !*listFlag && *minPriority != 0 {
+		log.Fatal("-min-priority requires -l")
+	}
+	if *listFlag && isFlagSet("f") && *format != "json" {
+		log.Fatal("-l supports only -f json")
+	}
+	if *oFlag != "" && !*listFlag && !*schemaFlag && *compareFlag == "" && !*allergenReport {
+		f, err := outputFormat(*oFlag, *format, isFlagSet("f"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		*format = f
+	}
+	if *allFlag && *recipePage != "" {
+		log.Fatal("cannot use -p with -all")
+	}
+	if *search != "" && (*allFlag || *recipePage != "") {
+		log.Fatal("cannot use -search with -all or -p")
+	}
+	if len(ids) > 0 && (*allFlag || *recipePage != "" || *search != "") {
+		log.Fatal("cannot use -ids with -all, -p, or -search")
+	}
+	if *includeRaw && (*allFlag || *search != "" || len(ids) > 0) {
+		log.Fatal("cannot use -include-raw with -all, -search, or -ids")
+	}
+	if *includeRaw && *format != "json" {
+		log.Fatal("-include-raw requires -f json")
+	}
+	if *sumNutrition && *format != "json" {
+		log.Fatal("-sum-nutrition requires -f json")
+	}
+	if len(certifyFreeOf) > 0 && *format != "json" {
+		log.Fatal("-certify-free-of requires -f json")
+	}
+	if *allergenReport && (*includeRaw || *sumNutrition || len(certifyFreeOf) > 0) {
+		log.Fatal("cannot use -allergen-report with -include-raw, -sum-nutrition, or -certify-free-of")
+	}
+	if *compareFlag != "" && (*allFlag || *recipePage != "") {
+		log.Fatal("cannot use -compare with -all or -p")
 	}
-	if *listFlag && *yieldIDsToNames {
-		log.Fatal("cannot use -y with -l")
+	if _, ok := recipe.LookupEncoder(*format); !ok {
+		log.Fatalf("unknown output format: %s", *format)
 	}
-	outfile := os.Stdout
-	if *oFlag != "" {
+	switch *sortFlag {
+	case "", "name", "ingredients", "protein-ratio":
+	default:
+		log.Fatalf("unknown sort order: %s", *sortFlag)
+	}
+	if *headFlag < 0 {
+		log.Fatal("-head must not be negative")
+	}
+	if *retries < 0 {
+		log.Fatal("-retries must not be negative")
+	}
+	if *chunk < 0 {
+		log.Fatal("-chunk must not be negative")
+	}
+	if *chunk > 0 && *oFlag == "" {
+		log.Fatal("-chunk requires -o")
+	}
+	if _, _, ok := parseS3URL(*oFlag); ok && *chunk > 0 {
+		log.Fatal("cannot use -chunk with an s3:// -o")
+	}
+	if strings.HasPrefix(*oFlag, "s3://") {
+		if _, _, ok := parseS3URL(*oFlag); !ok {
+			log.Fatalf("invalid S3 URL %s: want s3://bucket/key", *oFlag)
+		}
+	}
+	if *chunk > 0 && (*listFlag || *schemaFlag || *compareFlag != "" || *allergenReport || *includeRaw || *sumNutrition || len(certifyFreeOf) > 0) {
+		log.Fatal("cannot use -chunk with -l, -schema, -compare, -allergen-report, -include-raw, -sum-nutrition, or -certify-free-of")
+	}
+	recipe.DefaultScraper.Retries = *retries
+	var timings []recipe.PageMetrics
+	if *timing || *warnPagination {
+		var record *[]recipe.PageMetrics
+		if *timing {
+			record = &timings
+		}
+		recipe.DefaultScraper.OnPage = pageHook(record, *warnPagination, log.Printf)
+	}
+	var (
+		outfile = os.Stdout
+		upload  bytes.Buffer
+	)
+	bucket, key, toS3 := parseS3URL(*oFlag)
+	if toS3 {
+		output = bufio.NewWriter(&upload)
+	} else if *oFlag != "" && *chunk == 0 {
 		f, err := os.Create(*oFlag)
 		if err != nil {
 			log.Fatal(err)
 		}
 		outfile = f
 	}
-	output = bufio.NewWriter(outfile)
-	var data []byte
-	if *listFlag {
-		cs, err := recipe.Collections()
+	if !toS3 {
+		output = bufio.NewWriter(outfile)
+	}
+	var (
+		data     []byte
+		rs       recipe.Recipes
+		seen     []string
+		manifest map[string]time.Time
+		raw      []json.RawMessage
+		err      error
+	)
+	if *schemaFlag {
+		data, err = json.MarshalIndent(recipe.Schema(), "", "\t")
 		if err != nil {
 			log.Fatal(err)
 		}
-		for _, c := range cs {
-			data = append(data, []byte(c+"\n")...)
+	} else if *listFlag {
+		us, err := recipe.CollectionURLs(*sitemap)
+		if err != nil {
+			log.Fatal(err)
 		}
-	} else {
-		if *recipePage == "" {
-			*recipePage = "https://www.hellofresh.com/recipes"
-		} else if *recipePage != recipeHomePage {
-			isValid, err := recipe.IsValidPage(*recipePage)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if !isValid {
-				log.Fatalf("invalid recipe page: %s", *recipePage)
-			}
+		data, err = listCollections(recipe.FilterByMinPriority(us, *minPriority), isFlagSet("f"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if *compareFlag != "" {
+		var buf bytes.Buffer
+		err = writeComparison(&buf, strings.Split(*compareFlag, ","))
+		if err != nil {
+			log.Fatal(err)
 		}
-		rs, err := recipe.ScrapeRecipes(*recipePage)
+		data = buf.Bytes()
+	} else {
+		rs, raw, err = scrape()
 		if err != nil {
 			log.Fatal(err)
 		}
+		if *normalizeUnits {
+			rs.NormalizeUnits()
+		}
+		// Resolve IDs before filtering so that they can be looked up in
+		// any recipe of the scrape.
 		if *yieldIDsToNames {
 			err = rs.YieldIDsToNames()
 			if err != nil {
 				log.Fatal(err)
 			}
 		}
-		data, err = json.MarshalIndent(rs, "", "\t")
+		if len(excludeAllergen) > 0 || len(certifyFreeOf) > 0 || *allergenReport {
+			rs.ResolveAllergens()
+		}
+		if *seenFile != "" {
+			rs, seen, err = excludeSeen(*seenFile, rs)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *manifestFile != "" {
+			manifest, err = readManifest(*manifestFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			rs = rs.Changed(manifest)
+		}
+		if *dedupName {
+			rs = rs.DedupByName()
+		}
+		if len(categories) > 0 {
+			rs = rs.FilterByCategory(categories...)
+		}
+		if len(cuisines) > 0 {
+			rs = rs.FilterByCuisine(cuisines...)
+		}
+		if len(langs) > 0 {
+			rs = rs.FilterByLanguage(langs...)
+		}
+		if len(excludeAllergen) > 0 {
+			rs = rs.ExcludeAllergens(excludeAllergen...)
+		}
+		if *availableIn != "" {
+			rs = rs.AvailableIn(*availableIn)
+		}
+		if *boxOnly {
+			rs = rs.BoxOnly()
+		}
+		if *minRating > 0 {
+			rs = rs.FilterByMinRating(*minRating)
+		}
+		if *noSpicy {
+			rs = rs.ExcludeSpicy()
+		}
+		if *maxUtensils >= 0 {
+			rs = rs.FilterByMaxUtensils(*maxUtensils)
+		}
+		if *maxSteps >= 0 {
+			rs = rs.FilterByMaxSteps(*maxSteps)
+		}
+		switch *sortFlag {
+		case "name":
+			rs.SortByName()
+		case "ingredients":
+			rs.SortByIngredientCount()
+		case "protein-ratio":
+			rs.SortByProteinRatio()
+		}
+		if *timeBudget > 0 {
+			rs = rs.PlanWithinTime(*timeBudget)
+		}
+		if *headFlag > 0 {
+			rs = rs.Head(*headFlag)
+		}
+		if *sortIngredients {
+			for _, r := range rs {
+				for _, y := range r.Yields {
+					y.SortByAmount()
+				}
+			}
+		}
+		if *cookbook != "" {
+			err = createFile(*cookbook, rs.WriteCookbook)
+			if err != nil {
+				log.Fatalf("writing cookbook: %v", err)
+			}
+		}
+		if *archive != "" {
+			err = createFile(*archive, rs.WriteArchive)
+			if err != nil {
+				log.Fatalf("writing archive: %v", err)
+			}
+		}
+		if *qrDir != "" {
+			err = rs.WriteQRCodes(*qrDir)
+			if err != nil {
+				log.Fatalf("writing QR codes: %v", err)
+			}
+		}
+		if *allergenReport {
+			var buf bytes.Buffer
+			err = writeAllergenReport(&buf, rs.AllergenSummary())
+			data = buf.Bytes()
+		} else if *includeRaw || *sumNutrition || len(certifyFreeOf) > 0 {
+			out := jsonOutput{Recipes: rs}
+			if *includeRaw {
+				out.Debug = &debugData{Queries: raw}
+			}
+			if *sumNutrition {
+				out.TotalNutrition = rs.TotalNutrition()
+			}
+			if len(certifyFreeOf) > 0 {
+				out.FreeOf = rs.CertifyFreeOf(certifyFreeOf...)
+			}
+			data, err = json.MarshalIndent(out, "", "\t")
+		} else {
+			enc, _ := recipe.LookupEncoder(*format)
+			if *format == "rss" {
+				// The feed links to the scraped page rather than to the
+				// recipe home page.
+				enc = recipe.RSSEncoder{Title: "Hello Fresh recipes", Link: feedLink()}
+			}
+			if *chunk > 0 {
+				err = writeChunks(*oFlag, rs, *chunk, enc)
+			} else {
+				var buf bytes.Buffer
+				err = enc.Encode(&buf, rs)
+				data = buf.Bytes()
+			}
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
-	_, err := output.Write(data)
+	_, err = output.Write(data)
 	if err != nil {
 		log.Fatalf("writing recipe output: %v", err)
 	}
@@ -108,4 +632,337 @@ func main() {
 	if err != nil {
 		log.Fatalf("flushing recipe output: %v", err)
 	}
+	if toS3 {
+		err = uploadS3(context.Background(), bucket, key, upload.Bytes())
+		if err != nil {
+			log.Fatalf("uploading recipe output: %v", err)
+		}
+	}
+	if *timing {
+		err = writeTimings(os.Stderr, timings)
+		if err != nil {
+			log.Fatalf("writing timings: %v", err)
+		}
+	}
+	if *seenFile != "" {
+		err = writeSeen(*seenFile, seen, rs)
+		if err != nil {
+			log.Fatalf("writing seen recipes: %v", err)
+		}
+	}
+	if *manifestFile != "" {
+		err = writeManifest(*manifestFile, manifest, rs)
+		if err != nil {
+			log.Fatalf("writing manifest: %v", err)
+		}
+	}
+}
+
+// pageHook returns the OnPage hook for the -timing and -warn-pagination
+// flags. If timings is non-nil, the metrics of each page are appended to
+// it. If warn is set, pages that may be paginated are reported using
+// logf.
+func pageHook(timings *[]recipe.PageMetrics, warn bool, logf func(format string, v ...any)) func(recipe.PageMetrics) {
+	return func(m recipe.PageMetrics) {
+		if timings != nil {
+			*timings = append(*timings, m)
+		}
+		if warn && m.Err == nil && m.MayBePaginated() {
+			logf("%s: found exactly %d recipes; pagination may be incomplete", m.Page, m.Recipes)
+		}
+	}
+}
+
+// writeTimings writes a table of the fetch and parse times of each
+// scraped page to w.
+func writeTimings(w io.Writer, ms []recipe.PageMetrics) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "PAGE\tFETCH\tPARSE\tRECIPES\n")
+	var fetch, parse time.Duration
+	for _, m := range ms {
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%d\n", m.Page, m.Fetch.Round(time.Millisecond), m.Parse.Round(time.Millisecond), m.Recipes)
+		fetch += m.Fetch
+		parse += m.Parse
+	}
+	fmt.Fprintf(tw, "total\t%v\t%v\t\n", fetch.Round(time.Millisecond), parse.Round(time.Millisecond))
+	return tw.Flush()
+}
+
+// writeAllergenReport writes a table of each allergen and the number of
+// recipes containing it, most prevalent first.
+func writeAllergenReport(w io.Writer, summary map[string]int) error {
+	names := make([]string, 0, len(summary))
+	for name := range summary {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if summary[names[i]] != summary[names[j]] {
+			return summary[names[i]] > summary[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ALLERGEN\tRECIPES\n")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\n", name, summary[name])
+	}
+	return tw.Flush()
+}
+
+// readSeen reads the IDs of previously seen recipes from the JSON file
+// at path. A missing file holds no IDs.
+func readSeen(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	err = json.Unmarshal(b, &ids)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return ids, nil
+}
+
+// excludeSeen returns the recipes not listed in the seen file at path,
+// along with the IDs listed there.
+func excludeSeen(path string, rs recipe.Recipes) (recipe.Recipes, []string, error) {
+	seen, err := readSeen(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rs.ExcludeIDs(seen...), seen, nil
+}
+
+// writeSeen writes the seen IDs together with the IDs of the emitted
+// recipes to the JSON file at path.
+func writeSeen(path string, seen []string, rs recipe.Recipes) error {
+	for _, r := range rs {
+		seen = append(seen, r.ID)
+	}
+	b, err := json.MarshalIndent(seen, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// writeComparison writes a comparison of the first recipe on each of two
+// pages to w.
+func writeComparison(w io.Writer, pages []string) error {
+	if len(pages) != 2 {
+		return errors.New("-compare requires two URLs")
+	}
+	var rs [2]recipe.Recipe
+	for i, page := range pages {
+		prs, err := recipe.ScrapeRecipes(page)
+		if err != nil {
+			return err
+		}
+		if len(prs) == 0 {
+			return fmt.Errorf("no recipe found at %s", page)
+		}
+		rs[i] = prs[0]
+	}
+	return recipe.Compare(rs[0], rs[1]).Write(w)
+}
+
+// readManifest reads the manifest of recipe IDs and their last-seen
+// UpdatedAt from the JSON file at path. A missing file is an empty
+// manifest.
+func readManifest(path string) (map[string]time.Time, error) {
+	m := make(map[string]time.Time)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// writeManifest records the UpdatedAt of the emitted recipes in the
+// manifest and writes it to the JSON file at path.
+func writeManifest(path string, m map[string]time.Time, rs recipe.Recipes) error {
+	for _, r := range rs {
+		m[r.ID] = r.UpdatedAt
+	}
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// listCollections returns the listing of the collections printed by -l:
+// their URLs one per line, or if asJSON is set, a JSON array of the
+// sitemap entries and their metadata.
+func listCollections(us []recipe.URL, asJSON bool) ([]byte, error) {
+	if asJSON {
+		if us == nil {
+			us = []recipe.URL{}
+		}
+		return json.MarshalIndent(us, "", "\t")
+	}
+	var data []byte
+	for _, u := range us {
+		data = append(data, u.LOC+"\n"...)
+	}
+	return data, nil
+}
+
+// outputFormat returns the format to write the output file name in. An
+// explicit format, or any format if name has no extension, is returned
+// unchanged; otherwise the format is inferred from the extension.
+func outputFormat(name, format string, explicit bool) (string, error) {
+	ext := filepath.Ext(name)
+	if explicit || ext == "" {
+		return format, nil
+	}
+	f, ok := formatExtensions[strings.ToLower(ext)]
+	if !ok {
+		return "", fmt.Errorf("cannot infer output format from extension %s; use -f", ext)
+	}
+	return f, nil
+}
+
+// createFile writes the file name using write.
+func createFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeChunks encodes the recipes into numbered files of n recipes each,
+// named after base with a number inserted before its extension, so
+// "output.json" becomes "output-001.json", "output-002.json", and so on.
+func writeChunks(base string, rs recipe.Recipes, n int, enc recipe.Encoder) error {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for i := 0; i*n < len(rs); i++ {
+		end := (i + 1) * n
+		if end > len(rs) {
+			end = len(rs)
+		}
+		name := fmt.Sprintf("%s-%03d%s", stem, i+1, ext)
+		err := createFile(name, func(w io.Writer) error {
+			return enc.Encode(w, rs[i*n:end])
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseS3URL splits an s3://bucket/key URL into its bucket and key. The
+// boolean is false if name is not such a URL.
+func parseS3URL(name string) (bucket, key string, ok bool) {
+	rest, ok := strings.CutPrefix(name, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, key, ok = strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", false
+	}
+	return bucket, key, true
+}
+
+// uploadS3 uploads data to the S3 object key in bucket. Credentials,
+// region, and an endpoint for S3-compatible services are read from the
+// standard AWS environment variables and shared configuration files.
+// Custom endpoints are addressed path-style, as most S3-compatible
+// services expect.
+func uploadS3(ctx context.Context, bucket, key string, data []byte) error {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = o.BaseEndpoint != nil
+	})
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func feedLink() string {
+	if *recipePage == "" {
+		return recipeHomePage
+	}
+	return *recipePage
+}
+
+// scrape scrapes the recipes selected by the command-line flags. For a
+// single page, it also returns the raw payload query data.
+func scrape() (recipe.Recipes, []json.RawMessage, error) {
+	if *allFlag {
+		cs, err := recipe.CollectionsFromURL(*sitemap)
+		if err != nil {
+			return nil, nil, err
+		}
+		rs, skipped, err := recipe.ScrapeCollection(cs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if skipped > 0 {
+			log.Printf("skipped %d pages without recipes", skipped)
+		}
+		return rs, nil, nil
+	}
+	if *search != "" {
+		rs, err := recipe.Search(*domain, *search)
+		return rs, nil, err
+	}
+	if len(ids) > 0 {
+		rs, err := recipe.ScrapeByIDs(*domain, ids)
+		var unresolved *recipe.UnresolvedIDsError
+		if errors.As(err, &unresolved) {
+			log.Printf("could not resolve recipe IDs: %s", strings.Join(unresolved.IDs, ", "))
+			err = nil
+		}
+		return rs, nil, err
+	}
+	if *recipePage == "" {
+		*recipePage = recipeHomePage
+	} else if *recipePage != recipeHomePage {
+		isValid, err := recipe.IsValidPageFromURL(*recipePage, *sitemap)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isValid {
+			return nil, nil, fmt.Errorf("invalid recipe page: %s", *recipePage)
+		}
+	}
+	return recipe.ScrapeRaw(*recipePage)
 }
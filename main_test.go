@@ -0,0 +1,388 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewdargan/hello-fresh-scrape/recipe"
+)
+
+func TestStringSliceSet(t *testing.T) {
+	var s stringSlice
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&s, "exclude-allergen", "")
+	err := fs.Parse([]string{
+		"-exclude-allergen", "Milk,eggs, milk",
+		"-exclude-allergen", "EGGS,,Soy",
+		"-exclude-allergen", "soy",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := stringSlice{"milk", "eggs", "soy"}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("values = %q, want %q", s, want)
+	}
+	if got := s.String(); got != "milk,eggs,soy" {
+		t.Errorf("String() = %q, want milk,eggs,soy", got)
+	}
+}
+
+func TestIDList(t *testing.T) {
+	var l idList
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&l, "ids", "")
+	err := fs.Parse([]string{"-ids", "64aBc1, 64DeF2", "-ids", "64aBc1,64xYz3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := idList{"64aBc1", "64DeF2", "64xYz3"}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("values = %q, want %q", l, want)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		explicit bool
+		want     string
+		ok       bool
+	}{
+		{"recipes.csv", "json", false, "csv", true},
+		{"recipes.jsonl", "json", false, "ndjson", true},
+		{"out/recipes.MD", "json", false, "markdown", true},
+		{"gallery.htm", "json", false, "html", true},
+		{"fixture.go", "json", false, "gofixture", true},
+		{"s3://bucket/feed.rss", "json", false, "rss", true},
+		{"recipes", "json", false, "json", true},
+		{"recipes.csv", "ndjson", true, "ndjson", true},
+		{"recipes.txt", "checklist", true, "checklist", true},
+		{"recipes.txt", "json", false, "", false},
+	}
+	for _, tt := range tests {
+		got, err := outputFormat(tt.name, tt.format, tt.explicit)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("outputFormat(%q, %q, %v) = %q, %v, want %q, ok %v", tt.name, tt.format, tt.explicit, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestListCollections(t *testing.T) {
+	us := []recipe.URL{
+		{LOC: "https://www.hellofresh.com/recipes/quick-meals", LastMod: "2023-05-01", ChangeFreq: "weekly", Priority: 0.8},
+		{LOC: "https://www.hellofresh.com/recipes/vegan-recipes"},
+	}
+	data, err := listCollections(us, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"LOC": "https://www.hellofresh.com/recipes/quick-meals", "LastMod": "2023-05-01", "ChangeFreq": "weekly", "Priority": 0.8},
+		{"LOC": "https://www.hellofresh.com/recipes/vegan-recipes", "LastMod": "", "ChangeFreq": "", "Priority": 0.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON listing = %v, want %v", got, want)
+	}
+	data, err = listCollections(nil, true)
+	if err != nil || string(data) != "[]" {
+		t.Errorf("empty JSON listing = %s, %v, want []", data, err)
+	}
+	data, err = listCollections(us, false)
+	if want := "https://www.hellofresh.com/recipes/quick-meals\nhttps://www.hellofresh.com/recipes/vegan-recipes\n"; err != nil || string(data) != want {
+		t.Errorf("listing = %q, %v, want %q", data, err, want)
+	}
+}
+
+func TestSeen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	err := os.WriteFile(path, []byte(`["a", "c"]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := recipe.Recipes{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	unseen, seen, err := excludeSeen(path, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recipeIDs(unseen); !reflect.DeepEqual(got, []string{"b", "d"}) {
+		t.Errorf("first run emitted %v, want [b d]", got)
+	}
+	err = writeSeen(path, seen, unseen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unseen, _, err = excludeSeen(path, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unseen) != 0 {
+		t.Errorf("second run emitted %v, want none", recipeIDs(unseen))
+	}
+}
+
+func TestSeenMissingFile(t *testing.T) {
+	rs := recipe.Recipes{{ID: "a"}}
+	unseen, seen, err := excludeSeen(filepath.Join(t.TempDir(), "seen.json"), rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unseen) != 1 || seen != nil {
+		t.Errorf("excludeSeen with missing file = %v, %v, want all recipes and no IDs", recipeIDs(unseen), seen)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	may := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	rs := recipe.Recipes{{ID: "a", UpdatedAt: may}, {ID: "b", UpdatedAt: may}}
+	run := func(rs recipe.Recipes) []string {
+		t.Helper()
+		manifest, err := readManifest(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := rs.Changed(manifest)
+		err = writeManifest(path, manifest, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return recipeIDs(changed)
+	}
+	if got := run(rs); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("first run emitted %v, want [a b]", got)
+	}
+	rs[1].UpdatedAt = may.Add(24 * time.Hour)
+	if got := run(rs); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("second run emitted %v, want [b]", got)
+	}
+	if got := run(rs); len(got) != 0 {
+		t.Errorf("third run emitted %v, want none", got)
+	}
+}
+
+// recipesPage returns a page whose payload lists recipes with the given
+// IDs.
+func recipesPage(ids ...string) string {
+	items := make([]string, len(ids))
+	for i, id := range ids {
+		items[i] = fmt.Sprintf(`{"id":%q,"name":"Recipe %s"}`, id, id)
+	}
+	return `<html><head><script id="__NEXT_DATA__" type="application/json">` +
+		`{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":{"items":[` +
+		strings.Join(items, ",") + `]}}}]}}}}}</script></head><body></body></html>`
+}
+
+func TestWriteTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/one":
+			fmt.Fprint(w, recipesPage("a"))
+		case "/two":
+			fmt.Fprint(w, recipesPage("b", "c"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	var timings []recipe.PageMetrics
+	s := &recipe.Scraper{
+		Client: srv.Client(),
+		OnPage: func(m recipe.PageMetrics) { timings = append(timings, m) },
+	}
+	_, _, err := s.ScrapeCollection([]string{srv.URL + "/one", srv.URL + "/two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = writeTimings(&buf, timings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header, two pages, and a total:\n%s", len(lines), buf.String())
+	}
+	if f := strings.Fields(lines[0]); !reflect.DeepEqual(f, []string{"PAGE", "FETCH", "PARSE", "RECIPES"}) {
+		t.Errorf("header = %q", lines[0])
+	}
+	for i, want := range []struct {
+		page    string
+		recipes string
+	}{{srv.URL + "/one", "1"}, {srv.URL + "/two", "2"}} {
+		f := strings.Fields(lines[i+1])
+		if len(f) != 4 || f[0] != want.page || f[3] != want.recipes {
+			t.Errorf("row %d = %q, want page %s with %s recipes", i+1, lines[i+1], want.page, want.recipes)
+		}
+	}
+	if f := strings.Fields(lines[3]); len(f) != 3 || f[0] != "total" {
+		t.Errorf("total row = %q", lines[3])
+	}
+}
+
+func TestWriteChunks(t *testing.T) {
+	dir := t.TempDir()
+	rs := recipe.Recipes{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	enc, _ := recipe.LookupEncoder("ndjson")
+	err := writeChunks(filepath.Join(dir, "recipes.ndjson"), rs, 2, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"recipes-001.ndjson": {"a", "b"},
+		"recipes-002.ndjson": {"c", "d"},
+		"recipes-003.ndjson": {"e"},
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Errorf("got %d files, want %d", len(entries), len(want))
+	}
+	for name, wantIDs := range want {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			var r recipe.Recipe
+			err = json.Unmarshal([]byte(line), &r)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			got = append(got, r.ID)
+		}
+		if !reflect.DeepEqual(got, wantIDs) {
+			t.Errorf("%s holds %v, want %v", name, got, wantIDs)
+		}
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	tests := []struct {
+		name        string
+		bucket, key string
+		ok          bool
+	}{
+		{"s3://recipes/weekly.json", "recipes", "weekly.json", true},
+		{"s3://recipes/2023/05/weekly.json", "recipes", "2023/05/weekly.json", true},
+		{"s3://recipes/", "", "", false},
+		{"s3://recipes", "", "", false},
+		{"s3:///weekly.json", "", "", false},
+		{"recipes.json", "", "", false},
+		{"https://recipes.s3.amazonaws.com/weekly.json", "", "", false},
+	}
+	for _, tt := range tests {
+		bucket, key, ok := parseS3URL(tt.name)
+		if bucket != tt.bucket || key != tt.key || ok != tt.ok {
+			t.Errorf("parseS3URL(%q) = %q, %q, %v, want %q, %q, %v", tt.name, bucket, key, ok, tt.bucket, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestUploadS3(t *testing.T) {
+	var (
+		method, path string
+		body         []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	err := uploadS3(context.Background(), "recipes", "2023/weekly.json", []byte(`[{"ID":"a"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/recipes/2023/weekly.json" {
+		t.Errorf("request = %s %s, want PUT /recipes/2023/weekly.json", method, path)
+	}
+	if string(body) != `[{"ID":"a"}]` {
+		t.Errorf("body = %q, want the uploaded data", body)
+	}
+}
+
+func TestPageHookWarnsPagination(t *testing.T) {
+	full := make([]string, 20)
+	for i := range full {
+		full[i] = fmt.Sprintf("r%d", i)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/full":
+			fmt.Fprint(w, recipesPage(full...))
+		case "/partial":
+			fmt.Fprint(w, recipesPage(full[:7]...))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	var (
+		timings  []recipe.PageMetrics
+		warnings []string
+	)
+	s := &recipe.Scraper{
+		Client: srv.Client(),
+		OnPage: pageHook(&timings, true, func(format string, v ...any) {
+			warnings = append(warnings, fmt.Sprintf(format, v...))
+		}),
+	}
+	_, _, err := s.ScrapeCollection([]string{srv.URL + "/full", srv.URL + "/partial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{srv.URL + "/full: found exactly 20 recipes; pagination may be incomplete"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+	if len(timings) != 2 {
+		t.Errorf("recorded %d pages, want 2", len(timings))
+	}
+	s.OnPage = pageHook(nil, false, func(string, ...any) { t.Error("warned with -warn-pagination unset") })
+	_, err = s.ScrapeRecipes(srv.URL + "/full")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recipeIDs(rs recipe.Recipes) []string {
+	ids := make([]string, len(rs))
+	for i, r := range rs {
+		ids[i] = r.ID
+	}
+	return ids
+}
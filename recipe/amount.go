@@ -0,0 +1,125 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+var fractions = []struct {
+	value float64
+	glyph string
+}{
+	{1.0 / 4, "¼"},
+	{1.0 / 3, "⅓"},
+	{1.0 / 2, "½"},
+	{2.0 / 3, "⅔"},
+	{3.0 / 4, "¾"},
+}
+
+// FormatAmount formats an amount and its unit for display. Whole amounts
+// are written without decimals and common fractions use their Unicode
+// glyphs, so 1.5 "cup" becomes "1½ cup". Piece-like units such as "unit"
+// are omitted, since the amount alone is a count.
+func FormatAmount(amount float64, unit string) string {
+	s := formatNumber(amount)
+	if isPieceUnit(unit) {
+		return s
+	}
+	return s + " " + unit
+}
+
+func formatNumber(x float64) string {
+	whole, frac := math.Modf(x)
+	for _, f := range fractions {
+		if math.Abs(frac-f.value) < 0.01 {
+			if whole == 0 {
+				return f.glyph
+			}
+			return strconv.FormatFloat(whole, 'f', -1, 64) + f.glyph
+		}
+	}
+	return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64)
+}
+
+func isPieceUnit(unit string) bool {
+	u := NormalizeUnit(unit)
+	return u == "" || u == "unit"
+}
+
+// unitAliases maps the lowercased spellings of units to their canonical
+// form.
+var unitAliases = map[string]string{
+	"mg": "mg", "milligram": "mg", "milligrams": "mg",
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramm": "g",
+	"kg": "kg", "kilogram": "kg", "kilograms": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "centiliter": "cl", "centiliters": "cl",
+	"dl": "dl", "deciliter": "dl", "deciliters": "dl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tbs": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"unit": "unit", "units": "unit", "piece": "unit", "pieces": "unit", "pc": "unit", "pcs": "unit",
+	"kcal": "kcal", "calorie": "kcal", "calories": "kcal",
+	"kj": "kJ", "kilojoule": "kJ", "kilojoules": "kJ",
+}
+
+// NormalizeUnit returns the canonical form of a unit, so "gram", "grams",
+// and "gr" all become "g". Units without a known canonical form are
+// returned with surrounding space and any trailing period removed.
+func NormalizeUnit(u string) string {
+	u = strings.TrimSuffix(strings.TrimSpace(u), ".")
+	if c, ok := unitAliases[strings.ToLower(u)]; ok {
+		return c
+	}
+	return u
+}
+
+// NormalizeUnits replaces the units of the recipes' ingredient yields and
+// nutrition with their canonical forms as returned by NormalizeUnit.
+func (rs Recipes) NormalizeUnits() {
+	for _, r := range rs {
+		for _, y := range r.Yields {
+			for i := range y.Ingredients {
+				y.Ingredients[i].Unit = NormalizeUnit(y.Ingredients[i].Unit)
+			}
+		}
+		for i := range r.Nutrition {
+			r.Nutrition[i].Unit = NormalizeUnit(r.Nutrition[i].Unit)
+		}
+	}
+}
+
+// gramsPerUnit holds the weight in grams of one of each unit. Volumes
+// assume a density of 1 g/ml, as for water.
+var gramsPerUnit = map[string]float64{
+	"mg":   0.001,
+	"g":    1,
+	"kg":   1000,
+	"oz":   28.3495,
+	"lb":   453.592,
+	"ml":   1,
+	"cl":   10,
+	"dl":   100,
+	"l":    1000,
+	"tsp":  5,
+	"tbsp": 15,
+	"cup":  240,
+}
+
+// Grams returns the amount converted to grams. The boolean is false if
+// the unit, such as a piece count, cannot be converted.
+func (iy IngredientYield) Grams() (float64, bool) {
+	g, ok := gramsPerUnit[NormalizeUnit(iy.Unit)]
+	if !ok {
+		return 0, false
+	}
+	return iy.Amount * g, true
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import "testing"
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		unit   string
+		want   string
+	}{
+		{0.5, "cup", "½ cup"},
+		{0.5, "unit", "½"},
+		{2.0, "tbsp", "2 tbsp"},
+		{2.0, "g", "2 g"},
+		{2.0, "pieces", "2"},
+		{2.0, "", "2"},
+		{1.25, "cup", "1¼ cup"},
+		{1.25, "pc", "1¼"},
+		{1.5, "tsp", "1½ tsp"},
+		{0.333, "cup", "⅓ cup"},
+		{1.1, "oz", "1.1 oz"},
+		{250, "ml", "250 ml"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(tt.amount, tt.unit); got != tt.want {
+			t.Errorf("FormatAmount(%v, %q) = %q, want %q", tt.amount, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestGrams(t *testing.T) {
+	tests := []struct {
+		amount float64
+		unit   string
+		want   float64
+		ok     bool
+	}{
+		{200, "g", 200, true},
+		{200, "grams", 200, true},
+		{1.5, "kg", 1500, true},
+		{250, "ml", 250, true},
+		{2, "dl", 200, true},
+		{2, "tbsp", 30, true},
+		{3, "piece", 0, false},
+		{1, "unit", 0, false},
+		{1, "", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := IngredientYield{Amount: tt.amount, Unit: tt.unit}.Grams()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Grams() of %v %q = %v, %v, want %v, %v", tt.amount, tt.unit, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeUnit(t *testing.T) {
+	tests := []struct {
+		unit, want string
+	}{
+		{"g", "g"},
+		{"gram", "g"},
+		{"Grams", "g"},
+		{"gr.", "g"},
+		{" kg ", "kg"},
+		{"Tablespoons", "tbsp"},
+		{"tbs", "tbsp"},
+		{"tsp.", "tsp"},
+		{"millilitres", "ml"},
+		{"L", "l"},
+		{"pieces", "unit"},
+		{"KJ", "kJ"},
+		{"Calories", "kcal"},
+		{"pinch", "pinch"},
+		{" Bunch. ", "Bunch"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeUnit(tt.unit); got != tt.want {
+			t.Errorf("NormalizeUnit(%q) = %q, want %q", tt.unit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WriteArchive writes the recipes and their assets to w as a tar
+// archive. The archive holds the recipes as recipes.json, each recipe
+// image as images/<slug>.jpg, and each recipe card as cards/<slug>.pdf.
+// Recipes without an image or card link have no such entry. A recipe
+// whose slug is taken by an earlier recipe has its ID appended to the
+// slug.
+func (rs Recipes) WriteArchive(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	b, err := json.MarshalIndent(rs, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = writeTarFile(tw, "recipes.json", b, now)
+	if err != nil {
+		return err
+	}
+	names := rs.archiveNames()
+	for i, r := range rs {
+		assets := []struct{ link, name string }{
+			{r.ImageLink, "images/" + names[i] + ".jpg"},
+			{r.CardLink, "cards/" + names[i] + ".pdf"},
+		}
+		for _, a := range assets {
+			if a.link == "" {
+				continue
+			}
+			b, err := fetch(a.link)
+			if err != nil {
+				return err
+			}
+			err = writeTarFile(tw, a.name, b, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// archiveNames returns a distinct file name for each recipe.
+func (rs Recipes) archiveNames() []string {
+	names := make([]string, len(rs))
+	used := make(map[string]bool)
+	for i, r := range rs {
+		name := r.fileName()
+		if used[name] {
+			name += "-" + r.ID
+		}
+		base := name
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+func writeTarFile(tw *tar.Writer, name string, b []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(b)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+// fetch returns the body of the resource at link.
+func fetch(link string) ([]byte, error) {
+	resp, err := http.Get(link)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", link, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
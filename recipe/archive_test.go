@@ -0,0 +1,118 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	srv := servePages(t, map[string]string{
+		"/tacos.jpg": "jpeg data",
+		"/tacos.pdf": "%PDF-1.4",
+	})
+	rs := Recipes{
+		{ID: "a", Slug: "tacos-5f4d2a1b", ImageLink: srv.URL + "/tacos.jpg", CardLink: srv.URL + "/tacos.pdf"},
+		{ID: "b", Slug: "stew"},
+	}
+	var buf bytes.Buffer
+	err := rs.WriteArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	files := make(map[string][]byte)
+	tr := tar.NewReader(&buf)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		files[h.Name] = b
+	}
+	want := []string{"recipes.json", "images/tacos.jpg", "cards/tacos.pdf"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+	if got := string(files["images/tacos.jpg"]); got != "jpeg data" {
+		t.Errorf("image = %q, want jpeg data", got)
+	}
+	var archived Recipes
+	err = json.Unmarshal(files["recipes.json"], &archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 2 {
+		t.Errorf("recipes.json holds %d recipes, want 2", len(archived))
+	}
+}
+
+func TestWriteArchiveSharedSlug(t *testing.T) {
+	srv := servePages(t, map[string]string{
+		"/a.jpg": "image a",
+		"/b.jpg": "image b",
+		"/c.jpg": "image c",
+	})
+	rs := Recipes{
+		{ID: "a", Slug: "tacos-5f4d2a1b", ImageLink: srv.URL + "/a.jpg"},
+		{ID: "b", Slug: "tacos-64ab0c9e", ImageLink: srv.URL + "/b.jpg"},
+		{ID: "b", Slug: "tacos", ImageLink: srv.URL + "/c.jpg"},
+	}
+	var buf bytes.Buffer
+	err := rs.WriteArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := files[h.Name]; ok {
+			t.Errorf("duplicate entry %s", h.Name)
+		}
+		files[h.Name] = string(b)
+	}
+	want := map[string]string{
+		"images/tacos.jpg":     "image a",
+		"images/tacos-b.jpg":   "image b",
+		"images/tacos-b-2.jpg": "image c",
+	}
+	for name, data := range want {
+		if files[name] != data {
+			t.Errorf("%s = %q, want %q", name, files[name], data)
+		}
+	}
+}
+
+func TestWriteArchiveMissingAsset(t *testing.T) {
+	srv := servePages(t, nil)
+	rs := Recipes{{ID: "a", Slug: "tacos", ImageLink: srv.URL + "/missing.jpg"}}
+	if err := rs.WriteArchive(io.Discard); err == nil {
+		t.Error("WriteArchive with a missing asset succeeded, want error")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// A Comparison summarizes the differences between two recipes.
+type Comparison struct {
+	A, B   Recipe
+	Shared []string // ingredients used by both recipes
+	OnlyA  []string // ingredients used only by A
+	OnlyB  []string // ingredients used only by B
+}
+
+// Compare compares recipes a and b. Ingredients are matched by name.
+func Compare(a, b Recipe) Comparison {
+	c := Comparison{A: a, B: b}
+	inB := make(map[string]bool)
+	for _, ingred := range b.Ingredients {
+		inB[ingred.Name] = true
+	}
+	inA := make(map[string]bool)
+	for _, ingred := range a.Ingredients {
+		inA[ingred.Name] = true
+		if inB[ingred.Name] {
+			c.Shared = append(c.Shared, ingred.Name)
+		} else {
+			c.OnlyA = append(c.OnlyA, ingred.Name)
+		}
+	}
+	for _, ingred := range b.Ingredients {
+		if !inA[ingred.Name] {
+			c.OnlyB = append(c.OnlyB, ingred.Name)
+		}
+	}
+	return c
+}
+
+// Write writes the comparison to w as a side-by-side table of key
+// fields followed by the shared and unique ingredients.
+func (c Comparison) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	rows := [][3]string{
+		{"", c.A.Name, c.B.Name},
+		{"Prep time", c.A.PrepTime, c.B.PrepTime},
+		{"Total time", c.A.TotalTime, c.B.TotalTime},
+		{"Calories", caloriesField(c.A), caloriesField(c.B)},
+		{"Ingredients", strconv.Itoa(len(c.A.Ingredients)), strconv.Itoa(len(c.B.Ingredients))},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", row[0], row[1], row[2])
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\nShared: %s\nOnly in %s: %s\nOnly in %s: %s\n",
+		strings.Join(c.Shared, ", "), c.A.Name, strings.Join(c.OnlyA, ", "), c.B.Name, strings.Join(c.OnlyB, ", "))
+	return err
+}
+
+func caloriesField(r Recipe) string {
+	kcal, ok := r.calories()
+	if !ok {
+		return ""
+	}
+	return FormatAmount(kcal, "kcal")
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	a := Recipe{
+		Name:        "Tacos",
+		PrepTime:    "PT10M",
+		TotalTime:   "PT30M",
+		Nutrition:   []Nutrition{{Name: "Energy (kcal)", Amount: 650, Unit: "kcal"}},
+		Ingredients: []Ingredient{{Name: "Tortillas"}, {Name: "Beef"}, {Name: "Lime"}},
+	}
+	b := Recipe{
+		Name:        "Burrito",
+		PrepTime:    "PT15M",
+		TotalTime:   "PT40M",
+		Ingredients: []Ingredient{{Name: "Tortillas"}, {Name: "Rice"}, {Name: "Lime"}},
+	}
+	c := Compare(a, b)
+	if want := []string{"Tortillas", "Lime"}; !reflect.DeepEqual(c.Shared, want) {
+		t.Errorf("Shared = %v, want %v", c.Shared, want)
+	}
+	if want := []string{"Beef"}; !reflect.DeepEqual(c.OnlyA, want) {
+		t.Errorf("OnlyA = %v, want %v", c.OnlyA, want)
+	}
+	if want := []string{"Rice"}; !reflect.DeepEqual(c.OnlyB, want) {
+		t.Errorf("OnlyB = %v, want %v", c.OnlyB, want)
+	}
+	var buf bytes.Buffer
+	err := c.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `             Tacos     Burrito
+Prep time    PT10M     PT15M
+Total time   PT30M     PT40M
+Calories     650 kcal  
+Ingredients  3         3
+
+Shared: Tortillas, Lime
+Only in Tacos: Beef
+Only in Burrito: Rice
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
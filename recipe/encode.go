@@ -0,0 +1,206 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// An Encoder writes recipes to w in an output format.
+type Encoder interface {
+	Encode(w io.Writer, rs Recipes) error
+}
+
+// The EncoderFunc type is an adapter to allow the use of ordinary
+// functions as encoders.
+type EncoderFunc func(w io.Writer, rs Recipes) error
+
+// Encode calls f(w, rs).
+func (f EncoderFunc) Encode(w io.Writer, rs Recipes) error {
+	return f(w, rs)
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		"checklist": EncoderFunc(encodeChecklist),
+		"csv":       EncoderFunc(encodeCSV),
+		"families":  EncoderFunc(encodeFamilies),
+		"gofixture": EncoderFunc(encodeGoFixture),
+		"html":      EncoderFunc(encodeHTML),
+		"json":      EncoderFunc(encodeJSON),
+		"markdown":  EncoderFunc(encodeMarkdown),
+		"ndjson":    EncoderFunc(encodeNDJSON),
+		"rss":       RSSEncoder{Title: "Hello Fresh recipes", Link: "https://www.hellofresh.com/recipes"},
+	}
+)
+
+// RegisterEncoder makes an encoder available by the provided format
+// name, replacing any encoder already registered under that name.
+func RegisterEncoder(name string, e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[name] = e
+}
+
+// LookupEncoder returns the encoder registered under the format name.
+func LookupEncoder(name string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	e, ok := encoders[name]
+	return e, ok
+}
+
+// Formats returns a sorted list of the names of the registered
+// encoders.
+func Formats() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	var names []string
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// An RSSEncoder writes recipes as an RSS 2.0 feed with the given
+// channel title and link.
+type RSSEncoder struct {
+	Title string
+	Link  string
+}
+
+// Encode writes rs to w as an RSS feed.
+func (e RSSEncoder) Encode(w io.Writer, rs Recipes) error {
+	return rs.WriteRSS(w, e.Title, e.Link)
+}
+
+func encodeJSON(w io.Writer, rs Recipes) error {
+	b, err := json.MarshalIndent(rs, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func encodeNDJSON(w io.Writer, rs Recipes) error {
+	enc := json.NewEncoder(w)
+	for _, r := range rs {
+		err := enc.Encode(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var csvHeader = []string{"ID", "Name", "Headline", "Country", "Difficulty", "PrepTime", "TotalTime", "ServingSize", "Link"}
+
+func encodeCSV(w io.Writer, rs Recipes) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		err = cw.Write([]string{
+			r.ID,
+			r.Name,
+			r.Headline,
+			r.Country,
+			strconv.Itoa(r.Difficulty),
+			r.PrepTime,
+			r.TotalTime,
+			strconv.Itoa(r.ServingSize),
+			r.Link,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func encodeMarkdown(w io.Writer, rs Recipes) error {
+	for i, r := range rs {
+		if i > 0 {
+			_, err := fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "# %s\n\n", r.Name)
+		if err != nil {
+			return err
+		}
+		if r.Headline != "" {
+			_, err = fmt.Fprintf(w, "*%s*\n\n", r.Headline)
+			if err != nil {
+				return err
+			}
+		}
+		if r.DescriptionMarkdown != "" {
+			_, err = fmt.Fprintf(w, "%s\n\n", r.DescriptionMarkdown)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprint(w, "## Ingredients\n\n")
+		if err != nil {
+			return err
+		}
+		for _, line := range r.ingredientLines() {
+			_, err = fmt.Fprintf(w, "- %s\n", line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func encodeChecklist(w io.Writer, rs Recipes) error {
+	for i, r := range rs {
+		if i > 0 {
+			_, err := fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w, r.Name)
+		if err != nil {
+			return err
+		}
+		for _, line := range r.ingredientLines() {
+			_, err = fmt.Fprintf(w, "[ ] %s\n", line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func encodeFamilies(w io.Writer, rs Recipes) error {
+	b, err := json.MarshalIndent(rs.IngredientFamilies(), "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func encodeGoFixture(w io.Writer, rs Recipes) error {
+	return rs.WriteGoFixture(w, "recipe")
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("test-names", EncoderFunc(func(w io.Writer, rs Recipes) error {
+		for _, r := range rs {
+			_, err := fmt.Fprintf(w, "%s;", r.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	t.Cleanup(func() {
+		encodersMu.Lock()
+		defer encodersMu.Unlock()
+		delete(encoders, "test-names")
+	})
+	enc, ok := LookupEncoder("test-names")
+	if !ok {
+		t.Fatal(`LookupEncoder("test-names") not found after RegisterEncoder`)
+	}
+	var buf bytes.Buffer
+	err := enc.Encode(&buf, Recipes{{Name: "Tacos"}, {Name: "Stew"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Tacos;Stew;"; got != want {
+		t.Errorf("Encode = %q, want %q", got, want)
+	}
+	found := false
+	for _, f := range Formats() {
+		found = found || f == "test-names"
+	}
+	if !found {
+		t.Errorf("Formats() = %v, missing test-names", Formats())
+	}
+	if _, ok := LookupEncoder("no-such-format"); ok {
+		t.Error(`LookupEncoder("no-such-format") found an encoder`)
+	}
+}
+
+func TestEncodeChecklist(t *testing.T) {
+	rs := Recipes{
+		{
+			Name:        "Chicken Tacos",
+			Ingredients: []Ingredient{{ID: "i1", Name: "Tortillas"}, {ID: "i2", Name: "Chicken"}},
+			Yields:      []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "i1", Amount: 6, Unit: "unit"}, {ID: "i2", Amount: 250, Unit: "g"}}}},
+		},
+		{Name: "Salad", Ingredients: []Ingredient{{ID: "i3", Name: "Lettuce"}}},
+	}
+	var buf bytes.Buffer
+	err := encodeChecklist(&buf, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Chicken Tacos\n[ ] 6 Tortillas\n[ ] 250 g Chicken\n\nSalad\n[ ] Lettuce\n"
+	if got := buf.String(); got != want {
+		t.Errorf("encodeChecklist =\n%s\nwant\n%s", got, want)
+	}
+}
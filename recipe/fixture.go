@@ -0,0 +1,103 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// WriteGoFixture writes the recipes to w as Go source for package pkg
+// declaring them as a variable named recipes of type Recipes. This is
+// useful for turning scraped data into test fixtures. If pkg is recipe,
+// the source belongs to this package and its types are unqualified;
+// otherwise the source imports this package.
+func (rs Recipes) WriteGoFixture(w io.Writer, pkg string) error {
+	decl := []byte(fmt.Sprintf("package %s\n\nvar recipes = %#v\n", pkg, rs.fixtureData()))
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", decl, 0)
+	if err != nil {
+		return err
+	}
+	var (
+		qualifiers []int
+		imports    []string
+	)
+	usesTime := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		switch x.Name {
+		case "recipe":
+			qualifiers = append(qualifiers, fset.Position(x.Pos()).Offset)
+		case "time":
+			usesTime = true
+		}
+		return true
+	})
+	if usesTime {
+		imports = append(imports, "time")
+	}
+	if pkg == "recipe" {
+		// %#v qualifies types with the package name, which must be
+		// removed for the source to compile inside the package itself.
+		sort.Sort(sort.Reverse(sort.IntSlice(qualifiers)))
+		for _, off := range qualifiers {
+			decl = append(decl[:off], decl[off+len("recipe."):]...)
+		}
+	} else if len(qualifiers) > 0 {
+		imports = append(imports, reflect.TypeOf(Recipe{}).PkgPath())
+	}
+	if len(imports) > 0 {
+		var b bytes.Buffer
+		b.WriteString("\n\nimport (\n")
+		for _, path := range imports {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+		b.WriteString(")\n\n")
+		decl = bytes.Replace(decl, []byte("\n\n"), b.Bytes(), 1)
+	}
+	src, err := format.Source(decl)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// fixtureData returns a copy of the recipes that %#v formats as
+// compilable source: all times are in UTC, since %#v does not produce
+// compilable source for other locations.
+func (rs Recipes) fixtureData() Recipes {
+	cp := make(Recipes, len(rs))
+	for i, r := range rs {
+		r.CreatedAt = r.CreatedAt.UTC()
+		r.UpdatedAt = r.UpdatedAt.UTC()
+		ingreds := make([]Ingredient, len(r.Ingredients))
+		for j, ingred := range r.Ingredients {
+			ingred.Family.CreatedAt = ingred.Family.CreatedAt.UTC()
+			ingred.Family.UpdatedAt = ingred.Family.UpdatedAt.UTC()
+			ingreds[j] = ingred
+		}
+		if r.Ingredients != nil {
+			r.Ingredients = ingreds
+		}
+		cp[i] = r
+	}
+	return cp
+}
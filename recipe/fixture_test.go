@@ -0,0 +1,143 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkFixture type-checks the fixture src as package pkg and returns
+// the type of its recipes variable. A fixture for package recipe is
+// checked together with the sources of this package.
+func checkFixture(t *testing.T, src []byte, pkg string) (*ast.File, types.Type) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "fixture.go", src, 0)
+	if err != nil {
+		t.Fatalf("parsing fixture: %v\n%s", err, src)
+	}
+	files := []*ast.File{f}
+	if pkg == "recipe" {
+		names, err := filepath.Glob("*.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pf, err := parser.ParseFile(fset, name, b, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, pf)
+		}
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	p, err := conf.Check(pkg, fset, files, nil)
+	if err != nil {
+		t.Fatalf("type-checking fixture: %v\n%s", err, src)
+	}
+	obj := p.Scope().Lookup("recipes")
+	if obj == nil {
+		t.Fatalf("fixture declares no recipes variable:\n%s", src)
+	}
+	return f, obj.Type()
+}
+
+func TestWriteGoFixture(t *testing.T) {
+	rs := Recipes{{
+		ID:          "a",
+		Name:        "recipe.Name and \"quotes\"",
+		CreatedAt:   time.Date(2023, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Ingredients: []Ingredient{{ID: "i1", Name: "Rice"}},
+		Yields:      []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "i1", Amount: 150, Unit: "g"}}}},
+	}}
+	pkgPath := reflect.TypeOf(Recipe{}).PkgPath()
+	tests := []struct {
+		pkg       string
+		typ       string
+		imports   []string
+		qualified bool
+	}{
+		{"fixtures", pkgPath + ".Recipes", []string{pkgPath, "time"}, true},
+		{"recipe", "recipe.Recipes", []string{"time"}, false},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := rs.WriteGoFixture(&buf, tt.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, typ := checkFixture(t, buf.Bytes(), tt.pkg)
+		if typ.String() != tt.typ {
+			t.Errorf("%s: recipes has type %s, want %s", tt.pkg, typ, tt.typ)
+		}
+		var imports []string
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			imports = append(imports, path)
+		}
+		if !reflect.DeepEqual(imports, tt.imports) {
+			t.Errorf("%s: imports = %v, want %v", tt.pkg, imports, tt.imports)
+		}
+		var (
+			name      string
+			qualified bool
+		)
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if x, ok := n.X.(*ast.Ident); ok && x.Name == "recipe" {
+					qualified = true
+				}
+			case *ast.KeyValueExpr:
+				if k, ok := n.Key.(*ast.Ident); ok && k.Name == "Name" && name == "" {
+					if lit, ok := n.Value.(*ast.BasicLit); ok {
+						name, _ = strconv.Unquote(lit.Value)
+					}
+				}
+			}
+			return true
+		})
+		if qualified != tt.qualified {
+			t.Errorf("%s: fixture qualifies identifiers with recipe. = %v, want %v", tt.pkg, qualified, tt.qualified)
+		}
+		if name != rs[0].Name {
+			t.Errorf("%s: fixture name = %q, want %q", tt.pkg, name, rs[0].Name)
+		}
+	}
+}
+
+func TestWriteGoFixtureWithoutTime(t *testing.T) {
+	var buf bytes.Buffer
+	err := Recipes{}.WriteGoFixture(&buf, "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, typ := checkFixture(t, buf.Bytes(), "fixtures")
+	if len(f.Imports) != 1 {
+		t.Errorf("imports = %v, want only the recipe package", f.Imports)
+	}
+	if want := reflect.TypeOf(Recipe{}).PkgPath() + ".Recipes"; typ.String() != want {
+		t.Errorf("recipes has type %s, want %s", typ, want)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"html/template"
+	"io"
+)
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Hello Fresh recipes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #f7f7f2; color: #242424; }
+main { display: grid; grid-template-columns: repeat(auto-fill, minmax(18em, 1fr)); gap: 1.5em; }
+article { background: #fff; border-radius: 8px; overflow: hidden; box-shadow: 0 1px 4px rgba(0, 0, 0, 0.15); }
+article img { width: 100%; height: 12em; object-fit: cover; }
+article div { padding: 0 1em 1em; }
+h2 { font-size: 1.2em; }
+.headline { font-style: italic; }
+.times { color: #555; font-size: 0.9em; }
+ul { padding-left: 1.2em; }
+</style>
+</head>
+<body>
+<h1>Hello Fresh recipes</h1>
+<main>
+{{- range .}}
+<article>
+{{- if .ImageLink}}
+<img src="{{.ImageLink}}" alt="{{.Name}}">
+{{- end}}
+<div>
+<h2>{{if .Link}}<a href="{{.Link}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</h2>
+{{- if .Headline}}
+<p class="headline">{{.Headline}}</p>
+{{- end}}
+<p class="times">Prep time: {{.PrepTime}} &middot; Total time: {{.TotalTime}}</p>
+<ul>
+{{- range .Ingredients}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+</div>
+</article>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+type galleryRecipe struct {
+	Name        string
+	Link        string
+	ImageLink   string
+	Headline    string
+	PrepTime    string
+	TotalTime   string
+	Ingredients []string
+}
+
+func encodeHTML(w io.Writer, rs Recipes) error {
+	gs := make([]galleryRecipe, len(rs))
+	for i, r := range rs {
+		gs[i] = galleryRecipe{
+			Name:        r.Name,
+			Link:        r.Link,
+			ImageLink:   r.ImageLink,
+			Headline:    r.Headline,
+			PrepTime:    r.PrepTime,
+			TotalTime:   r.TotalTime,
+			Ingredients: r.ingredientLines(),
+		}
+	}
+	return galleryTemplate.Execute(w, gs)
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestEncodeHTML(t *testing.T) {
+	rs := Recipes{
+		{Name: "Chicken Tacos", Link: "https://www.hellofresh.com/recipes/chicken-tacos"},
+		{Name: "Beef Stew", Headline: "with mashed potatoes"},
+		{Name: "<script>alert(1)</script> Pie"},
+	}
+	var buf bytes.Buffer
+	err := encodeHTML(&buf, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, name := range []string{"Chicken Tacos", "Beef Stew", "&lt;script&gt;alert(1)&lt;/script&gt; Pie"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("output does not contain %q", name)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("output contains an unescaped <script> tag")
+	}
+	doc, err := html.Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("output does not parse: %v", err)
+	}
+	var articles int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "article" {
+			articles++
+		}
+		if n.Type == html.ElementNode && n.Data == "script" {
+			t.Error("output has a script element")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if articles != len(rs) {
+		t.Errorf("got %d articles, want %d", articles, len(rs))
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NutritionPer100g rescales the recipe nutrition, which Hello Fresh
+// reports per serving, to values per 100g of food. The serving weight
+// is derived from the ingredient yields; an error is returned if it
+// cannot be determined. Ingredients whose units cannot be converted to
+// grams, such as piece counts, are left out of the serving weight, so
+// for recipes using them the values are overstated.
+func (r Recipe) NutritionPer100g() ([]Nutrition, error) {
+	w, err := r.servingWeight()
+	if err != nil {
+		return nil, err
+	}
+	ns := make([]Nutrition, len(r.Nutrition))
+	for i, n := range r.Nutrition {
+		n.Amount = n.Amount * 100 / w
+		ns[i] = n
+	}
+	return ns, nil
+}
+
+// kJPerKcal is the number of kilojoules in a kilocalorie.
+const kJPerKcal = 4.184
+
+// ConvertEnergyUnit converts the recipe's energy nutrition entries to
+// the given unit, either "kJ" or "kcal".
+func (r *Recipe) ConvertEnergyUnit(to string) error {
+	var factor float64
+	switch strings.ToLower(to) {
+	case "kj":
+		to, factor = "kJ", kJPerKcal
+	case "kcal":
+		to, factor = "kcal", 1/kJPerKcal
+	default:
+		return fmt.Errorf("unknown energy unit %s", to)
+	}
+	for i, n := range r.Nutrition {
+		unit := strings.ToLower(n.Unit)
+		if unit != "kj" && unit != "kcal" || unit == strings.ToLower(to) {
+			continue
+		}
+		r.Nutrition[i].Amount = n.Amount * factor
+		r.Nutrition[i].Unit = to
+	}
+	return nil
+}
+
+// ProteinCalorieRatio returns the grams of protein per kilocalorie of
+// the recipe. An error is returned if either value is missing.
+func (r Recipe) ProteinCalorieRatio() (float64, error) {
+	kcal, ok := r.calories()
+	if !ok || kcal == 0 {
+		return 0, fmt.Errorf("calories of recipe %s unknown", r.ID)
+	}
+	for _, n := range r.Nutrition {
+		if strings.EqualFold(n.Name, "protein") && strings.EqualFold(n.Unit, "g") {
+			return n.Amount / kcal, nil
+		}
+	}
+	return 0, fmt.Errorf("protein of recipe %s unknown", r.ID)
+}
+
+// calories returns the energy of a serving in kilocalories.
+func (r Recipe) calories() (float64, bool) {
+	for _, n := range r.Nutrition {
+		if strings.EqualFold(n.Unit, "kcal") {
+			return n.Amount, true
+		}
+	}
+	for _, n := range r.Nutrition {
+		if strings.EqualFold(n.Unit, "kj") {
+			return n.Amount / kJPerKcal, true
+		}
+	}
+	return 0, false
+}
+
+// servingWeight returns the weight in grams of a single serving, using
+// the first yield with ingredients convertible to grams. Ingredients that
+// cannot be converted are skipped, so the weight may be understated.
+func (r Recipe) servingWeight() (float64, error) {
+	for _, y := range r.Yields {
+		if y.Yields <= 0 {
+			continue
+		}
+		var g float64
+		for _, iy := range y.Ingredients {
+			if w, ok := iy.Grams(); ok {
+				g += w
+			}
+		}
+		if g > 0 {
+			return g / float64(y.Yields), nil
+		}
+	}
+	return 0, fmt.Errorf("weight of recipe %s unknown", r.ID)
+}
+
+// TotalNutrition sums the nutrition entries of the recipes, such as the
+// meals of a day. Entries are matched by name and unit, so a nutrient
+// reported in different units is kept as separate entries. The totals
+// are returned in the order they are first seen.
+func (rs Recipes) TotalNutrition() []Nutrition {
+	type key struct{ name, unit string }
+	var total []Nutrition
+	index := make(map[key]int)
+	for _, r := range rs {
+		for _, n := range r.Nutrition {
+			k := key{strings.ToLower(n.Name), strings.ToLower(n.Unit)}
+			if i, ok := index[k]; ok {
+				total[i].Amount += n.Amount
+				continue
+			}
+			index[k] = len(total)
+			total = append(total, n)
+		}
+	}
+	return total
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNutritionPer100g(t *testing.T) {
+	r := Recipe{
+		ID: "r",
+		Nutrition: []Nutrition{
+			{Name: "Energy (kcal)", Amount: 600, Unit: "kcal"},
+			{Name: "Protein", Amount: 30, Unit: "g"},
+		},
+		Yields: []Yield{{
+			Yields: 2,
+			Ingredients: []IngredientYield{
+				{ID: "rice", Amount: 600, Unit: "g"},
+				{ID: "stock", Amount: 0.2, Unit: "l"},
+				{ID: "lime", Amount: 1, Unit: "unit"},
+			},
+		}},
+	}
+	// A serving weighs (600 + 200) / 2 = 400g; the lime is skipped.
+	got, err := r.NutritionPer100g()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{150, 7.5}
+	for i, n := range got {
+		if math.Abs(n.Amount-want[i]) > 1e-9 {
+			t.Errorf("%s per 100g = %v, want %v", n.Name, n.Amount, want[i])
+		}
+	}
+	if r.Nutrition[0].Amount != 600 {
+		t.Errorf("NutritionPer100g modified the recipe nutrition")
+	}
+}
+
+func TestNutritionPer100gUnknownWeight(t *testing.T) {
+	r := Recipe{
+		ID:        "r",
+		Nutrition: []Nutrition{{Name: "Energy (kcal)", Amount: 600, Unit: "kcal"}},
+		Yields:    []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "egg", Amount: 2, Unit: "unit"}}}},
+	}
+	if _, err := r.NutritionPer100g(); err == nil {
+		t.Error("NutritionPer100g with only piece units succeeded, want error")
+	}
+}
+
+func TestConvertEnergyUnit(t *testing.T) {
+	r := Recipe{Nutrition: []Nutrition{
+		{Name: "Energy (kJ)", Amount: 2000, Unit: "kJ"},
+		{Name: "Fat", Amount: 20, Unit: "g"},
+	}}
+	err := r.ConvertEnergyUnit("kcal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Nutrition[0]; n.Unit != "kcal" || math.Abs(n.Amount-478.01) > 0.01 {
+		t.Errorf("2000 kJ converted to %v %s, want 478.01 kcal", n.Amount, n.Unit)
+	}
+	if n := r.Nutrition[1]; n.Unit != "g" || n.Amount != 20 {
+		t.Errorf("fat converted to %v %s, want unchanged", n.Amount, n.Unit)
+	}
+	if err := r.ConvertEnergyUnit("joules"); err == nil {
+		t.Error("ConvertEnergyUnit(joules) succeeded, want error")
+	}
+}
+
+func TestTotalNutrition(t *testing.T) {
+	rs := Recipes{
+		{Nutrition: []Nutrition{
+			{Name: "Energy (kcal)", Amount: 650, Unit: "kcal"},
+			{Name: "Sodium", Amount: 800, Unit: "mg"},
+		}},
+		{Nutrition: []Nutrition{
+			{Name: "energy (kcal)", Amount: 550, Unit: "KCAL"},
+			{Name: "Sodium", Amount: 1.2, Unit: "g"},
+		}},
+	}
+	want := []Nutrition{
+		{Name: "Energy (kcal)", Amount: 1200, Unit: "kcal"},
+		{Name: "Sodium", Amount: 800, Unit: "mg"},
+		{Name: "Sodium", Amount: 1.2, Unit: "g"},
+	}
+	if got := rs.TotalNutrition(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TotalNutrition() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var errNoRecipeProps = errors.New("recipe props data not found")
+
+// queriesPath is the path of object keys leading from the root of the
+// recipe props payload to its list of queries.
+var queriesPath = []string{"props", "pageProps", "ssrPayload", "dehydratedState", "queries"}
+
+// parseQueries finds the recipe props payload in the HTML read from r
+// and calls decode with a decoder positioned at the data of each of its
+// queries; decode must consume exactly that value. The payload is
+// decoded directly from r, so neither the page nor the payload is held
+// in memory as a whole.
+//
+// Pages streamed by the Next.js app router lack the single payload
+// script and instead push the payload in chunks. If no payload script is
+// found, the chunks are reassembled and searched for the queries.
+func parseQueries(r io.Reader, decode func(*json.Decoder) error) error {
+	z := html.NewTokenizer(r)
+	var (
+		inScript bool
+		chunks   []string
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return z.Err()
+			}
+			if len(chunks) > 0 {
+				return decodeChunkQueries(strings.Join(chunks, ""), decode)
+			}
+			return errNoRecipeProps
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			inScript = string(tn) == "script"
+			if inScript && hasAttr && isRecipeProps(z) {
+				// The tokenizer has read ahead of the start tag, so the
+				// script content begins with its buffered input.
+				return decodeQueries(io.MultiReader(bytes.NewReader(z.Buffered()), r), decode)
+			}
+		case html.TextToken:
+			if inScript {
+				if c, ok := pushChunk(z.Text()); ok {
+					chunks = append(chunks, c)
+				}
+			}
+		case html.EndTagToken:
+			inScript = false
+		}
+	}
+}
+
+// isRecipeProps reports whether the attributes of the current script
+// start tag of z mark the script holding the recipe props payload.
+func isRecipeProps(z *html.Tokenizer) bool {
+	k, v, moreAttr := z.TagAttr()
+	if string(k) != "id" || string(v) != "__NEXT_DATA__" || !moreAttr {
+		return false
+	}
+	k, v, _ = z.TagAttr()
+	return string(k) == "type" && string(v) == "application/json"
+}
+
+func decodeQueries(r io.Reader, decode func(*json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+	for _, key := range queriesPath {
+		found, err := findKey(dec, key)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("recipe props queries: unexpected %v", tok)
+	}
+	for dec.More() {
+		err = decodeQuery(dec, decode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeQuery calls decode with dec positioned at the state data of the
+// query at the current position of dec, then consumes the rest of the
+// query. Queries without data are skipped.
+func decodeQuery(dec *json.Decoder, decode func(*json.Decoder) error) error {
+	found, err := findKey(dec, "state")
+	if err != nil || !found {
+		return err
+	}
+	found, err = findKey(dec, "data")
+	if err != nil {
+		return err
+	}
+	if found {
+		err = decode(dec)
+		if err != nil {
+			return err
+		}
+		// Consume the rest of the state.
+		err = skipRest(dec, true)
+		if err != nil {
+			return err
+		}
+	}
+	return skipRest(dec, true)
+}
+
+// findKey advances dec to the value of key in the object at the current
+// position. Keys match case-insensitively, as with json.Unmarshal. It
+// reports whether the key was found; if not, the value at the current
+// position is consumed.
+func findKey(dec *json.Decoder, key string) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == json.Delim('[') {
+		return false, skipRest(dec, false)
+	}
+	if tok != json.Delim('{') {
+		return false, nil
+	}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if k, ok := tok.(string); ok && strings.EqualFold(k, key) {
+			return true, nil
+		}
+		err = skipValue(dec)
+		if err != nil {
+			return false, err
+		}
+	}
+	_, err = dec.Token()
+	return false, err
+}
+
+// discard is a JSON value that is parsed but not stored.
+type discard struct{}
+
+func (*discard) UnmarshalJSON([]byte) error { return nil }
+
+// skipValue consumes the value at the current position of dec without
+// storing it. Unlike walking its tokens, this does not allocate the
+// strings it holds.
+func skipValue(dec *json.Decoder) error {
+	var v discard
+	return dec.Decode(&v)
+}
+
+// skipRest consumes the rest of the object or array dec is in, including
+// its closing delimiter. inObject reports whether dec is in an object,
+// whose keys are consumed along with their values.
+func skipRest(dec *json.Decoder, inObject bool) error {
+	for dec.More() {
+		if inObject {
+			_, err := dec.Token()
+			if err != nil {
+				return err
+			}
+		}
+		err := skipValue(dec)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// pushChunk returns the payload chunk pushed by a script of the form
+// self.__next_f.push([1,"chunk"]).
+func pushChunk(script []byte) (string, bool) {
+	const prefix = "self.__next_f.push("
+	s := strings.TrimSpace(string(script))
+	if !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	var args []json.RawMessage
+	err := json.Unmarshal([]byte(s[len(prefix):len(s)-1]), &args)
+	if err != nil || len(args) != 2 || string(args[0]) != "1" {
+		return "", false
+	}
+	var chunk string
+	err = json.Unmarshal(args[1], &chunk)
+	if err != nil {
+		return "", false
+	}
+	return chunk, true
+}
+
+// decodeChunkQueries finds the dehydrated query state in the reassembled
+// streaming payload and calls decode with a decoder of the data of each
+// of its queries.
+// The payload consists of lines of the form id:value, where values that
+// are JSON may hold the state at any depth.
+func decodeChunkQueries(payload string, decode func(*json.Decoder) error) error {
+	for _, line := range strings.Split(payload, "\n") {
+		_, value, ok := strings.Cut(line, ":")
+		if !ok || value == "" || value[0] != '[' && value[0] != '{' {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		var v any
+		if dec.Decode(&v) != nil {
+			continue
+		}
+		state, ok := findDehydratedState(v)
+		if !ok {
+			continue
+		}
+		qs, _ := lookup(state, "queries").([]any)
+		for _, q := range qs {
+			d := lookup(lookup(q, "state"), "data")
+			if d == nil {
+				continue
+			}
+			b, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			err = decode(json.NewDecoder(bytes.NewReader(b)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errNoRecipeProps
+}
+
+// findDehydratedState searches v depth-first for the value of a
+// dehydratedState key.
+func findDehydratedState(v any) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		if s := lookup(v, "dehydratedState"); s != nil {
+			return s, true
+		}
+		for _, e := range v {
+			if s, ok := findDehydratedState(e); ok {
+				return s, true
+			}
+		}
+	case []any:
+		for _, e := range v {
+			if s, ok := findDehydratedState(e); ok {
+				return s, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookup returns the value of key in v if v is an object. Keys match
+// case-insensitively, as with json.Unmarshal.
+func lookup(v any, key string) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for k, e := range m {
+		if strings.EqualFold(k, key) {
+			return e
+		}
+	}
+	return nil
+}
+
+// queryRecipes returns the recipes held in the data of a payload query.
+func queryRecipes(b json.RawMessage) (Recipes, error) {
+	return decodeRecipes(json.NewDecoder(bytes.NewReader(b)))
+}
+
+// decodeRecipes decodes the recipes held in the query data at the
+// current position of dec. Listings hold their recipes in an items
+// array, which is decoded one recipe at a time; individual recipe pages
+// carry the recipe itself as the data.
+func decodeRecipes(dec *json.Decoder) (Recipes, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	// Recipes only occur when the data is a JSON object
+	if tok == json.Delim('[') {
+		return nil, skipRest(dec, false)
+	}
+	if tok != json.Delim('{') {
+		return nil, nil
+	}
+	var rs Recipes
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		if strings.EqualFold(key, "items") {
+			rs, err = decodeItems(dec)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var v json.RawMessage
+		err = dec.Decode(&v)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = v
+	}
+	_, err = dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) > 0 {
+		return rs, nil
+	}
+	// Queries other than recipes hold all sorts of data, so the fields
+	// are only decoded as a recipe when they look like one. A recipe that
+	// does not decode is not a recipe.
+	if !isRecipe(fields) {
+		return nil, nil
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var r Recipe
+	if json.Unmarshal(b, &r) != nil {
+		return nil, nil
+	}
+	if r.ID != "" && len(r.Ingredients) > 0 {
+		return Recipes{r}, nil
+	}
+	return nil, nil
+}
+
+// isRecipe reports whether fields has a string id and an array of
+// ingredients.
+func isRecipe(fields map[string]json.RawMessage) bool {
+	var id, ingredients bool
+	for k, v := range fields {
+		if len(v) == 0 {
+			continue
+		}
+		switch {
+		case strings.EqualFold(k, "id"):
+			id = v[0] == '"'
+		case strings.EqualFold(k, "ingredients"):
+			ingredients = v[0] == '['
+		}
+	}
+	return id && ingredients
+}
+
+// decodeItems decodes the array of recipes at the current position of
+// dec.
+func decodeItems(dec *json.Decoder) (Recipes, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("recipe items: unexpected %v", tok)
+	}
+	var rs Recipes
+	for dec.More() {
+		// Decode in place to avoid a copy of each recipe.
+		rs = append(rs, Recipe{})
+		err = dec.Decode(&rs[len(rs)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = dec.Token()
+	return rs, err
+}
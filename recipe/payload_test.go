@@ -0,0 +1,388 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// baselinePayload is the parser parseQueries replaced, as ScrapeRecipes
+// ran it on a response body: it reads the whole payload script,
+// unmarshals it in one go and keeps the items of each query. It serves
+// as the reference for the streaming parser. Unlike the original, it
+// skips queries without data instead of indexing into them, and it
+// returns the data of the queries it kept.
+func baselinePayload(r io.Reader) (Recipes, []json.RawMessage, error) {
+	b, err := baselineRecipeProps(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var p struct {
+		Props struct {
+			PageProps struct {
+				SSRPayload struct {
+					DehydratedState struct {
+						Queries []struct {
+							State struct {
+								Data json.RawMessage
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		rs  Recipes
+		raw []json.RawMessage
+	)
+	for _, q := range p.Props.PageProps.SSRPayload.DehydratedState.Queries {
+		if len(q.State.Data) == 0 {
+			continue
+		}
+		raw = append(raw, q.State.Data)
+		// Recipes only occur when Data is a JSON object
+		if q.State.Data[0] == '{' {
+			var d struct {
+				Items []Recipe
+			}
+			err = json.Unmarshal(q.State.Data, &d)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(d.Items) > 0 {
+				rs = append(rs, d.Items...)
+			}
+		}
+	}
+	return rs, raw, nil
+}
+
+// baselineRecipeProps is the parseRecipeProps that baselinePayload
+// reads the payload script with.
+func baselineRecipeProps(r io.Reader) ([]byte, error) {
+	z := html.NewTokenizer(r)
+	isRecipeProps := false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return nil, z.Err()
+		case html.TextToken:
+			if isRecipeProps {
+				return z.Text(), nil
+			}
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			if string(tn) == "script" && hasAttr {
+				k, v, moreAttr := z.TagAttr()
+				if string(k) == "id" && string(v) == "__NEXT_DATA__" && moreAttr {
+					k, v, _ = z.TagAttr()
+					if string(k) == "type" && string(v) == "application/json" {
+						isRecipeProps = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// fetchPage serves page and fetches it with Scraper.fetch. If wantRaw
+// is set, the raw queries are returned too.
+func fetchPage(t *testing.T, page string, wantRaw bool) (Recipes, []json.RawMessage, error) {
+	t.Helper()
+	srv := servePages(t, map[string]string{"/recipes": page})
+	s := &Scraper{Client: srv.Client()}
+	rs, raw, _, err := s.fetch(srv.URL+"/recipes", wantRaw, &PageMetrics{})
+	return rs, raw, err
+}
+
+func nextDataPage(payload string) string {
+	return `<html><head><script id="__NEXT_DATA__" type="application/json">` + payload + `</script></head><body></body></html>`
+}
+
+var payloadTests = []struct {
+	name    string
+	payload string
+	recipes int
+}{
+	{
+		name:    "items",
+		payload: `{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":` + itemsData("a", "b") + `}}]}}}}}`,
+		recipes: 2,
+	},
+	{
+		name: "reordered keys",
+		payload: `{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"mutations":[],"queries":[` +
+			`{"queryKey":["recipes"],"state":{"status":"success","data":` + itemsData("a") + `,"dataUpdatedAt":1}},` +
+			`{"state":{"dataUpdatedAt":2,"data":` + itemsData("b") + `}}` +
+			`]},"locale":"en-US"},"isServer":true}},"page":"/recipes"}`,
+		recipes: 2,
+	},
+	{
+		name: "siblings before props",
+		payload: `{"buildId":"x","query":{"props":{"pageProps":{}}},"runtimeConfig":{"a":[1,{"b":null}]},` +
+			`"props":{"__N_SSP":true,"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":` + itemsData("a") + `}}]}}}}}`,
+		recipes: 1,
+	},
+	{
+		name:    "case-insensitive keys",
+		payload: `{"Props":{"PageProps":{"SSRPayload":{"DehydratedState":{"Queries":[{"State":{"Data":` + itemsData("a") + `}}]}}}}}`,
+		recipes: 1,
+	},
+	{
+		name:    "null queries",
+		payload: `{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":null}}}}}`,
+	},
+	{
+		name:    "missing queries",
+		payload: `{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{}}}}}`,
+	},
+	{
+		name:    "missing props",
+		payload: `{"page":"/recipes"}`,
+	},
+	{
+		name:    "null and non-object data",
+		payload: `{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":null}},{"state":{}},{"state":{"data":[1,2]}},{"state":{"data":"text"}}]}}}}}`,
+	},
+}
+
+func TestFetchMatchesBaseline(t *testing.T) {
+	for _, tt := range payloadTests {
+		page := nextDataPage(tt.payload)
+		wantRecipes, wantRaw, err := baselinePayload(strings.NewReader(page))
+		if err != nil {
+			t.Fatalf("%s: reference parser: %v", tt.name, err)
+		}
+		for _, raw := range []bool{false, true} {
+			rs, gotRaw, err := fetchPage(t, page, raw)
+			if err != nil {
+				t.Errorf("%s (raw %v): %v", tt.name, raw, err)
+				continue
+			}
+			if len(rs) != tt.recipes {
+				t.Errorf("%s (raw %v): got %d recipes, want %d", tt.name, raw, len(rs), tt.recipes)
+			}
+			if !reflect.DeepEqual(rs, wantRecipes) {
+				t.Errorf("%s (raw %v): recipes differ from the baseline parser:\ngot  %+v\nwant %+v", tt.name, raw, rs, wantRecipes)
+			}
+			if raw && !reflect.DeepEqual(gotRaw, wantRaw) {
+				t.Errorf("%s: raw queries = %s, want %s", tt.name, gotRaw, wantRaw)
+			}
+		}
+	}
+}
+
+func TestParseQueriesForeignQueries(t *testing.T) {
+	// Queries other than recipes have fields whose types conflict
+	// with those of Recipe.
+	foreign := []string{
+		`{"id":5,"name":"menu"}`,
+		`{"tags":"x"}`,
+		`{"id":"m","ingredients":"none"}`,
+		`{"id":"m","ingredients":[{"id":"i1"}],"name":["menu"]}`,
+	}
+	var queries []string
+	for _, data := range foreign {
+		queries = append(queries, `{"state":{"data":`+data+`}}`)
+	}
+	queries = append(queries, `{"state":{"data":`+itemsData("a", "b")+`}}`)
+	page := nextDataPage(`{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[` + strings.Join(queries, ",") + `]}}}}}`)
+	for _, raw := range []bool{false, true} {
+		rs, _, err := fetchPage(t, page, raw)
+		if err != nil {
+			t.Errorf("raw %v: %v", raw, err)
+			continue
+		}
+		if got, want := ids(rs), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("raw %v: recipes = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestFetchSingleRecipe(t *testing.T) {
+	page := nextDataPage(`{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":{"id":"a","name":"Tacos","ingredients":[{"id":"i1","name":"Tortillas"}]}}}]}}}}}`)
+	for _, raw := range []bool{false, true} {
+		rs, _, err := fetchPage(t, page, raw)
+		if err != nil {
+			t.Errorf("raw %v: %v", raw, err)
+			continue
+		}
+		if len(rs) != 1 || rs[0].Name != "Tacos" || len(rs[0].Ingredients) != 1 {
+			t.Errorf("raw %v: recipes = %+v, want the Tacos recipe", raw, rs)
+		}
+	}
+}
+
+func TestParseQueriesNoPayload(t *testing.T) {
+	_, _, err := fetchPage(t, `<html><body><script>var x = 1;</script></body></html>`, false)
+	if err != errNoRecipeProps {
+		t.Errorf("err = %v, want %v", err, errNoRecipeProps)
+	}
+}
+
+// largePayloadPage returns a page whose payload lists n recipes with
+// realistic amounts of nested data.
+func largePayloadPage(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<html><head><title>Recipes</title></head><body><div>`)
+	b.WriteString(strings.Repeat(`<p class="teaser">Fresh ingredients delivered.</p>`, 200))
+	b.WriteString(`</div><script id="__NEXT_DATA__" type="application/json">{"buildId":"abc","props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[{"state":{"data":{"locale":"en-US"}}},{"state":{"data":{"items":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":"r%d","name":"Recipe %d","slug":"recipe-%d","headline":"with a side","description":"%s","prepTime":"PT15M","totalTime":"PT35M","country":"US",`,
+			i, i, i, strings.Repeat("Tasty and quick. ", 10))
+		b.WriteString(`"ingredients":[`)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"id":"i%d","name":"Ingredient %d","allergens":["al1"],"family":{"id":"f%d","name":"Family","priority":%d,"usageByCountry":{"US":10,"DE":3}}}`, j, j, j%3, j%3)
+		}
+		b.WriteString(`],"yields":[{"yields":2,"ingredients":[`)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"id":"i%d","amount":%d,"unit":"g"}`, j, 10*j)
+		}
+		b.WriteString(`]}],"nutrition":[{"type":"kcal","name":"Energy (kcal)","amount":650,"unit":"kcal"},{"name":"Protein","amount":30,"unit":"g"}],`)
+		b.WriteString(`"allergens":[{"id":"al1","name":"Milk","slug":"milk"}],"tags":[{"id":"t1","name":"Quick","slug":"quick"}]}`)
+	}
+	b.WriteString(`]}}}]}}}}}</script></body></html>`)
+	return b.Bytes()
+}
+
+func TestParseQueriesLargePayload(t *testing.T) {
+	page := largePayloadPage(50)
+	want, _, err := baselinePayload(bytes.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := fetchPage(t, string(page), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 50 || !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch returned %d recipes differing from the baseline parser", len(got))
+	}
+}
+
+// The benchmarks fetch the same page with Scraper.fetch and with the
+// baseline parser. Streaming the payload allocates about a quarter fewer
+// bytes, since neither the page nor the payload is held as a whole, while
+// the number of allocations stays about the same: both decode every
+// recipe field.
+
+func BenchmarkFetch(b *testing.B) {
+	page := largePayloadPage(200)
+	srv := servePages(b, map[string]string{"/recipes": string(page)})
+	s := &Scraper{Client: srv.Client()}
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _, err := s.fetch(srv.URL+"/recipes", false, &PageMetrics{})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBaselineFetch(b *testing.B) {
+	page := largePayloadPage(200)
+	srv := servePages(b, map[string]string{"/recipes": string(page)})
+	c := srv.Client()
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := c.Get(srv.URL + "/recipes")
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _, err = baselinePayload(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// pushPage returns a page streamed by the Next.js app router that pushes
+// the given payload chunks.
+func pushPage(chunks ...string) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><script>(self.__next_f=self.__next_f||[]).push([0])</script></head><body>`)
+	for _, c := range chunks {
+		s, _ := json.Marshal(c)
+		fmt.Fprintf(&b, `<script>self.__next_f.push([1,%s])</script>`, s)
+	}
+	b.WriteString(`<script>console.log("done")</script></body></html>`)
+	return b.String()
+}
+
+func TestParseQueriesPushChunks(t *testing.T) {
+	state := `{"dehydratedState":{"mutations":[],"queries":[{"state":{"data":{"locale":"en-US"}}},{"state":{"data":` + itemsData("a", "b") + `}}]}}`
+	payload := "0:[\"$\",\"html\",null,{}]\n" +
+		"1:I[\"chunks/app.js\",\"default\"]\n" +
+		`2:["$","div",null,{"children":["$","RecipesPage",null,` + state + `]}]` + "\n"
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{"single push", []string{payload}},
+		{"one push per line", strings.SplitAfter(payload, "\n")},
+		{"state split across pushes", splitEvery(payload, 17)},
+	}
+	for _, tt := range tests {
+		for _, raw := range []bool{false, true} {
+			rs, gotRaw, err := fetchPage(t, pushPage(tt.chunks...), raw)
+			if err != nil {
+				t.Errorf("%s (raw %v): %v", tt.name, raw, err)
+				continue
+			}
+			if got := ids(rs); !reflect.DeepEqual(got, []string{"a", "b"}) {
+				t.Errorf("%s (raw %v): recipes = %v, want [a b]", tt.name, raw, got)
+			}
+			if raw && len(gotRaw) != 2 {
+				t.Errorf("%s: got %d raw queries, want 2", tt.name, len(gotRaw))
+			}
+		}
+	}
+}
+
+func TestParseQueriesPushChunksNoState(t *testing.T) {
+	page := pushPage("0:[\"$\",\"html\",null,{}]\n", `1:{"title":"Recipes"}`+"\n")
+	_, _, err := fetchPage(t, page, false)
+	if err != errNoRecipeProps {
+		t.Errorf("err = %v, want %v", err, errNoRecipeProps)
+	}
+}
+
+// splitEvery splits s into chunks of n bytes.
+func splitEvery(s string, n int) []string {
+	var chunks []string
+	for len(s) > n {
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+	return append(chunks, s)
+}
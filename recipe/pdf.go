@@ -0,0 +1,108 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// WriteCookbook writes the recipes to w as a PDF cookbook with one
+// recipe per page listing its name, ingredients, and nutrition.
+func (rs Recipes) WriteCookbook(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	utf8Tr := pdf.UnicodeTranslatorFromDescriptor("")
+	tr := func(s string) string { return utf8Tr(cookbookText(s)) }
+	for _, r := range rs {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 18)
+		pdf.MultiCell(0, 9, tr(r.Name), "", "L", false)
+		if r.Headline != "" {
+			pdf.SetFont("Helvetica", "I", 12)
+			pdf.MultiCell(0, 6, tr(r.Headline), "", "L", false)
+		}
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, "Ingredients", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		for _, line := range r.ingredientLines() {
+			pdf.MultiCell(0, 6, tr(line), "", "L", false)
+		}
+		if len(r.Nutrition) > 0 {
+			pdf.Ln(4)
+			pdf.SetFont("Helvetica", "B", 14)
+			pdf.CellFormat(0, 8, "Nutrition", "", 1, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 11)
+			for _, n := range r.Nutrition {
+				pdf.CellFormat(60, 6, tr(n.Name), "", 0, "L", false, 0, "")
+				pdf.CellFormat(0, 6, tr(FormatAmount(n.Amount, n.Unit)), "", 1, "L", false, 0, "")
+			}
+		}
+	}
+	return pdf.Output(w)
+}
+
+// cookbookText replaces the characters of s that the cp1252 encoding of
+// the core PDF fonts cannot represent. Fractions such as ⅓ are spelled
+// out as 1/3, letters lose accents that cp1252 lacks, and any other
+// character becomes a question mark.
+func cookbookText(s string) string {
+	var b strings.Builder
+	var prev rune
+	for _, c := range s {
+		if _, ok := charmap.Windows1252.EncodeRune(c); ok {
+			b.WriteRune(c)
+			prev = c
+			continue
+		}
+		d := norm.NFKD.String(string(c))
+		if unicode.Is(unicode.No, c) && '0' <= prev && prev <= '9' {
+			// Keep the whole number apart from the fraction, so 1⅓ does not
+			// read as 11/3.
+			b.WriteByte(' ')
+		}
+		written := false
+		for _, dc := range d {
+			if dc == '⁄' {
+				b.WriteByte('/')
+				written = true
+			} else if _, ok := charmap.Windows1252.EncodeRune(dc); ok {
+				b.WriteRune(dc)
+				written = true
+			}
+		}
+		if !written {
+			b.WriteByte('?')
+		}
+		prev = c
+	}
+	return b.String()
+}
+
+// ingredientLines describes the recipe ingredients with the amounts of
+// its first yield. If the recipe has no yields, only the ingredient
+// names are listed.
+func (r Recipe) ingredientLines() []string {
+	var lines []string
+	if len(r.Yields) == 0 {
+		for _, ingred := range r.Ingredients {
+			lines = append(lines, ingred.Name)
+		}
+		return lines
+	}
+	for _, iy := range r.Yields[0].Ingredients {
+		name, err := ingredientName(iy.ID, r.Ingredients)
+		if err != nil {
+			name = iy.ID
+		}
+		lines = append(lines, FormatAmount(iy.Amount, iy.Unit)+" "+name)
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCookbook(t *testing.T) {
+	rs := Recipes{
+		{
+			Name:        "Crème Brûlée",
+			Headline:    "with raspberries",
+			Ingredients: []Ingredient{{ID: "i1", Name: "Cream"}},
+			Yields:      []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "i1", Amount: 0.5, Unit: "cup"}}}},
+			Nutrition:   []Nutrition{{Name: "Energy (kcal)", Amount: 420, Unit: "kcal"}},
+		},
+		{
+			Name:        "Tacos",
+			Ingredients: []Ingredient{{ID: "i2", Name: "Tortillas"}, {ID: "i3", Name: "Jalapeño"}},
+			Yields:      []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "i2", Amount: 6, Unit: "unit"}, {ID: "i3", Amount: 1.333, Unit: "cup"}}}},
+		},
+	}
+	name := filepath.Join(t.TempDir(), "cookbook.pdf")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rs.WriteCookbook(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) == 0 {
+		t.Fatal("cookbook is empty")
+	}
+	if !bytes.HasPrefix(b, []byte("%PDF-")) {
+		t.Errorf("cookbook does not start with %%PDF-")
+	}
+	if n := bytes.Count(b, []byte("/Type /Page\n")); n != len(rs) {
+		t.Errorf("cookbook has %d pages, want %d", n, len(rs))
+	}
+	text := pdfContent(t, b)
+	// The core fonts use cp1252, in which è is 0xE8 and ñ is 0xF1.
+	for _, want := range []string{"Cr\xe8me Br\xfbl\xe9e", "\xbd cup Cream", "1 1/3 cup Jalape\xf1o"} {
+		if !bytes.Contains(text, []byte(want)) {
+			t.Errorf("cookbook text does not contain %q", want)
+		}
+	}
+}
+
+// pdfContent returns the inflated content streams of the PDF b.
+func pdfContent(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var content []byte
+	for {
+		i := bytes.Index(b, []byte("stream\n"))
+		if i < 0 {
+			return content
+		}
+		b = b[i+len("stream\n"):]
+		j := bytes.Index(b, []byte("endstream"))
+		if j < 0 {
+			t.Fatal("unterminated PDF stream")
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(b[:j]))
+		if err == nil {
+			s, _ := io.ReadAll(zr)
+			content = append(content, s...)
+		}
+		b = b[j+len("endstream"):]
+	}
+}
+
+func TestCookbookText(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"Crème Brûlée", "Crème Brûlée"},
+		{"½ cup", "½ cup"},
+		{"⅓ cup", "1/3 cup"},
+		{"1⅔ cup", "1 2/3 cup"},
+		{"⅛ tsp", "1/8 tsp"},
+		{"Kraków Żurek", "Kraków Zurek"},
+		{"Pho 🍜", "Pho ?"},
+	}
+	for _, tt := range tests {
+		if got := cookbookText(tt.s); got != tt.want {
+			t.Errorf("cookbookText(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanWithinTime greedily selects recipes whose combined total time fits
+// within budget. It prefers variety: a first pass selects recipes from
+// cuisines not yet selected, and a second pass fills the remaining time
+// with any recipe that fits. Recipes are considered in order, and those
+// without a valid total time are skipped.
+func (rs Recipes) PlanWithinTime(budget time.Duration) Recipes {
+	var (
+		plan     Recipes
+		used     time.Duration
+		selected = make([]bool, len(rs))
+		cuisines = make(map[string]bool)
+	)
+	for pass := 0; pass < 2; pass++ {
+		for i, r := range rs {
+			if selected[i] {
+				continue
+			}
+			d, err := r.TotalDuration()
+			if err != nil || used+d > budget {
+				continue
+			}
+			key := r.varietyKey()
+			if pass == 0 && cuisines[key] {
+				continue
+			}
+			selected[i] = true
+			cuisines[key] = true
+			used += d
+			plan = append(plan, r)
+		}
+	}
+	return plan
+}
+
+// varietyKey identifies the kind of dish a recipe is, for the purpose
+// of planning a varied selection.
+func (r Recipe) varietyKey() string {
+	if len(r.Cuisines) > 0 {
+		return r.Cuisines[0].Slug
+	}
+	return r.Category.Slug
+}
+
+// TotalDuration returns the recipe's total time, which Hello Fresh
+// reports as an ISO 8601 duration such as PT35M.
+func (r Recipe) TotalDuration() (time.Duration, error) {
+	return parseISODuration(r.TotalTime)
+}
+
+// parseISODuration parses an ISO 8601 duration of days, hours, minutes,
+// and seconds, such as P1DT2H30M or PT45M.
+func parseISODuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var (
+		d      time.Duration
+		inTime bool
+		num    string
+		parsed bool
+	)
+	for _, c := range rest {
+		var unit time.Duration
+		switch {
+		case '0' <= c && c <= '9' || c == '.':
+			num += string(c)
+			continue
+		case c == 'T' && !inTime && num == "":
+			inTime = true
+			continue
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n * float64(unit))
+		num = ""
+		parsed = true
+	}
+	if num != "" || !parsed {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"PT45M", 45 * time.Minute, true},
+		{"PT1H30M", 90 * time.Minute, true},
+		{"PT2H", 2 * time.Hour, true},
+		{"PT90S", 90 * time.Second, true},
+		{"P1DT2H30M", 26*time.Hour + 30*time.Minute, true},
+		{"P2D", 48 * time.Hour, true},
+		{"PT0.5H", 30 * time.Minute, true},
+		{"", 0, false},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"45M", 0, false},
+		{"PT45", 0, false},
+		{"P1H", 0, false},
+		{"PT1D", 0, false},
+		{"PTXM", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseISODuration(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseISODuration(%q) = %v, %v, want %v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPlanWithinTime(t *testing.T) {
+	cuisine := func(slug string) []Cuisine { return []Cuisine{{Slug: slug}} }
+	rs := Recipes{
+		{ID: "tacos", TotalTime: "PT30M", Cuisines: cuisine("mexican")},
+		{ID: "burrito", TotalTime: "PT20M", Cuisines: cuisine("mexican")},
+		{ID: "roast", TotalTime: "PT2H", Cuisines: cuisine("british")},
+		{ID: "curry", TotalTime: "PT40M", Cuisines: cuisine("indian")},
+		{ID: "unknown", TotalTime: "", Cuisines: cuisine("thai")},
+		{ID: "salad", TotalTime: "PT15M", Cuisines: cuisine("mexican")},
+	}
+	budget := 90 * time.Minute
+	plan := rs.PlanWithinTime(budget)
+	var used time.Duration
+	for _, r := range plan {
+		d, err := r.TotalDuration()
+		if err != nil {
+			t.Errorf("planned recipe %s without a valid total time", r.ID)
+		}
+		used += d
+	}
+	if used > budget {
+		t.Errorf("plan %v takes %v, over the budget of %v", ids(plan), used, budget)
+	}
+	want := []string{"tacos", "curry", "burrito"}
+	if got := ids(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanWithinTime(%v) = %v, want %v", budget, got, want)
+	}
+	if got := rs.PlanWithinTime(10 * time.Minute); len(got) != 0 {
+		t.Errorf("PlanWithinTime(10m) = %v, want none", ids(got))
+	}
+}
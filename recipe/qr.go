@@ -0,0 +1,32 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// WriteQRCodes writes a PNG QR code of each recipe's link to
+// dir/<slug>.png, creating dir if necessary. Recipes without a link are
+// skipped.
+func (rs Recipes) WriteQRCodes(dir string) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		if r.Link == "" {
+			continue
+		}
+		err = qrcode.WriteFile(r.Link, qrcode.Medium, 256, filepath.Join(dir, r.fileName()+".png"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteQRCodes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qr")
+	rs := Recipes{
+		{ID: "a", Slug: "spicy-tacos-5f4d2a1b", Link: "https://www.hellofresh.com/recipes/spicy-tacos-5f4d2a1b"},
+		{ID: "b", Slug: "no-link"},
+	}
+	err := rs.WriteQRCodes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Name() != "spicy-tacos.png" {
+		t.Fatalf("files = %v, want only spicy-tacos.png", es)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, es[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Error("QR code is not a PNG")
+	}
+}
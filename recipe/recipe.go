@@ -12,29 +12,65 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
-
-	"golang.org/x/net/html"
 )
 
+// A URLSet is a sitemap listing recipe collection URLs. Its struct tags
+// name elements without a namespace, so they match both plain sitemaps
+// and sitemaps declaring the standard xmlns namespace or a prefixed one.
 type URLSet struct {
-	XMLName xml.Name `xml:"urlset"`
+	XMLName xml.Name `xml:"urlset" json:"-"`
 	URLs    []URL    `xml:"url"`
 }
 
+// A URL is a sitemap entry for a recipe collection.
 type URL struct {
-	XMLName    xml.Name `xml:"url"`
+	XMLName    xml.Name `xml:"url" json:"-"`
 	LOC        string   `xml:"loc"`
 	LastMod    string   `xml:"lastmod"`
 	ChangeFreq string   `xml:"changefreq"`
 	Priority   float64  `xml:"priority"`
 }
 
+// SitemapURL is the URL of the Hello Fresh recipe collections sitemap.
+const SitemapURL = "https://www.hellofresh.com/sitemap_recipe_collections.xml"
+
 // Collections scrapes a list of recipe collections from the Hello Fresh
 // website.
 func Collections() ([]string, error) {
-	resp, err := http.Get("https://www.hellofresh.com/sitemap_recipe_collections.xml")
+	return CollectionsFromURL(SitemapURL)
+}
+
+// CollectionsFromURL scrapes a list of recipe collections from the
+// sitemap at the provided URL.
+func CollectionsFromURL(sitemap string) ([]string, error) {
+	us, err := CollectionURLs(sitemap)
+	if err != nil {
+		return nil, err
+	}
+	var collection []string
+	for _, u := range us {
+		collection = append(collection, u.LOC)
+	}
+	return collection, nil
+}
+
+// CollectionURLs scrapes the recipe collection entries, including their
+// metadata, from the sitemap at the provided URL. Entries without a
+// priority have a Priority of 0.
+func CollectionURLs(sitemap string) ([]URL, error) {
+	u, err := url.ParseRequestURI(sitemap)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid sitemap URL: %s", sitemap)
+	}
+	resp, err := http.Get(sitemap)
 	if err != nil {
 		return nil, err
 	}
@@ -48,17 +84,31 @@ func Collections() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	var collection []string
-	for _, url := range urlset.URLs {
-		collection = append(collection, url.LOC)
+	return urlset.URLs, nil
+}
+
+// FilterByMinPriority returns the sitemap entries whose priority is at
+// least min. Entries without a priority have a Priority of 0.
+func FilterByMinPriority(us []URL, min float64) []URL {
+	var kept []URL
+	for _, u := range us {
+		if u.Priority >= min {
+			kept = append(kept, u)
+		}
 	}
-	return collection, nil
+	return kept
 }
 
 // IsValidPage tests whether the provided page is a valid Hello Fresh
 // recipe page.
 func IsValidPage(page string) (bool, error) {
-	cs, err := Collections()
+	return IsValidPageFromURL(page, SitemapURL)
+}
+
+// IsValidPageFromURL tests whether the provided page belongs to one of
+// the recipe collections in the sitemap at the provided URL.
+func IsValidPageFromURL(page, sitemap string) (bool, error) {
+	cs, err := CollectionsFromURL(sitemap)
 	if err != nil {
 		return false, err
 	}
@@ -70,32 +120,13 @@ func IsValidPage(page string) (bool, error) {
 	return false, nil
 }
 
-type payload struct {
-	Props struct {
-		PageProps struct {
-			SSRPayload struct {
-				DehydratedState struct {
-					Queries []struct {
-						State struct {
-							Data json.RawMessage
-						}
-					}
-				}
-			}
-		}
-	}
-}
-
-type data struct {
-	Items []Recipe
-}
-
 type Recipe struct {
 	ID                  string
 	Country             string
 	Name                string
 	SeoName             string
 	Category            Category
+	Categories          []Category
 	Slug                string
 	Headline            string
 	Description         string
@@ -121,10 +152,66 @@ type Recipe struct {
 	Tags                []Tag
 	Cuisines            []Cuisine
 	Yields              []Yield
+	Steps               []Step
+	Rating              float64
+	RatingCount         int
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Payloads carry either a
+// single category or a list of them, so Category and Categories are
+// populated from whichever is present. Ratings are read from the
+// payload's averageRating and ratingsCount fields.
+func (r *Recipe) UnmarshalJSON(b []byte) error {
+	type plain Recipe
+	aux := struct {
+		*plain
+		AverageRating *float64
+		RatingsCount  *int
+	}{plain: (*plain)(r)}
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+	if aux.AverageRating != nil {
+		r.Rating = *aux.AverageRating
+	}
+	if aux.RatingsCount != nil {
+		r.RatingCount = *aux.RatingsCount
+	}
+	if len(r.Categories) == 0 && r.Category.ID != "" {
+		r.Categories = []Category{r.Category}
+	} else if r.Category.ID == "" && len(r.Categories) > 0 {
+		r.Category = r.Categories[0]
+	}
+	return nil
 }
 
 type Recipes []Recipe
 
+// AbsoluteLinks resolves the recipe's relative links against base, the
+// URL of the page the recipe was scraped from. If the recipe has no
+// image link, one is resolved from its image path.
+func (r *Recipe) AbsoluteLinks(base string) error {
+	b, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if r.ImageLink == "" {
+		r.ImageLink = r.ImagePath
+	}
+	for _, link := range []*string{&r.Link, &r.ImageLink, &r.CardLink, &r.VideoLink} {
+		if *link == "" {
+			continue
+		}
+		u, err := url.Parse(*link)
+		if err != nil {
+			return err
+		}
+		*link = b.ResolveReference(u).String()
+	}
+	return nil
+}
+
 type Category struct {
 	ID       string
 	Type     string
@@ -229,77 +316,85 @@ type IngredientYield struct {
 	Unit   string
 }
 
+// A Step is a cooking step of a recipe. Ingredients and Utensils hold
+// the IDs of the recipe ingredients and utensils used in the step.
+type Step struct {
+	Index                int
+	Instructions         string
+	InstructionsHTML     string
+	InstructionsMarkdown string
+	Ingredients          []string
+	Utensils             []string
+	Images               []StepImage
+}
+
+type StepImage struct {
+	Link    string
+	Path    string
+	Caption string
+}
+
 // ScrapeRecipes scrapes recipes from the JSON payload on the
 // Hello Fresh website.
+//
+// ScrapeRecipes is a wrapper around DefaultScraper.ScrapeRecipes.
 func ScrapeRecipes(page string) (Recipes, error) {
-	resp, err := http.Get(page)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	b, err := parseRecipeProps(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	var p payload
-	err = json.Unmarshal(b, &p)
-	if err != nil {
-		return nil, err
-	}
-	var rs Recipes
-	for _, q := range p.Props.PageProps.SSRPayload.DehydratedState.Queries {
-		// Recipes only occur when Data is a JSON object
-		if q.State.Data[0] == '{' {
-			var d data
-			err = json.Unmarshal(q.State.Data, &d)
-			if err != nil {
-				return nil, err
-			}
-			if len(d.Items) > 0 {
-				rs = append(rs, d.Items...)
-			}
-		}
-	}
-	return rs, nil
+	return DefaultScraper.ScrapeRecipes(page)
 }
 
-func parseRecipeProps(r io.Reader) ([]byte, error) {
-	z := html.NewTokenizer(r)
-	isRecipeProps := false
-	for {
-		tt := z.Next()
-		switch tt {
-		case html.ErrorToken:
-			return nil, z.Err()
-		case html.TextToken:
-			if isRecipeProps {
-				return z.Text(), nil
-			}
-		case html.StartTagToken:
-			tn, hasAttr := z.TagName()
-			if string(tn) == "script" && hasAttr {
-				k, v, moreAttr := z.TagAttr()
-				if string(k) == "id" && string(v) == "__NEXT_DATA__" && moreAttr {
-					k, v, _ = z.TagAttr()
-					if string(k) == "type" && string(v) == "application/json" {
-						isRecipeProps = true
-					}
-				}
-			}
-		}
-	}
-	return nil, errors.New("recipe props data not found")
+// ScrapeRaw is like ScrapeRecipes but also returns the raw data of each
+// query in the payload.
+//
+// ScrapeRaw is a wrapper around DefaultScraper.ScrapeRaw.
+func ScrapeRaw(page string) (Recipes, []json.RawMessage, error) {
+	return DefaultScraper.ScrapeRaw(page)
+}
+
+// Search returns the recipes matching query using the search of the Hello
+// Fresh website at domain, such as www.hellofresh.com.
+//
+// Search is a wrapper around DefaultScraper.Search.
+func Search(domain, query string) (Recipes, error) {
+	return DefaultScraper.Search(domain, query)
+}
+
+// ScrapeByIDs scrapes the recipes with the given IDs from the Hello Fresh
+// website at domain.
+//
+// ScrapeByIDs is a wrapper around DefaultScraper.ScrapeByIDs.
+func ScrapeByIDs(domain string, ids []string) (Recipes, error) {
+	return DefaultScraper.ScrapeByIDs(domain, ids)
+}
+
+// ScrapeCollection scrapes recipes from each of the provided pages,
+// skipping pages that yield no recipes.
+//
+// ScrapeCollection is a wrapper around DefaultScraper.ScrapeCollection.
+func ScrapeCollection(pages []string) (Recipes, int, error) {
+	return DefaultScraper.ScrapeCollection(pages)
 }
 
 // YieldIDsToNames converts recipe IngredientYield IDs to their
-// respective names.
+// respective names. IDs missing from a recipe's own ingredients, as is
+// common for base sauces, are looked up among the ingredients of all the
+// recipes, so it should be called before the recipes are filtered.
 func (rs Recipes) YieldIDsToNames() error {
+	index := make(map[string]Ingredient)
+	for _, r := range rs {
+		for _, ingred := range r.Ingredients {
+			index[ingred.ID] = ingred
+		}
+	}
 	for _, r := range rs {
 		for _, ys := range r.Yields {
 			for i, ingred := range ys.Ingredients {
 				name, err := ingredientName(ingred.ID, r.Ingredients)
 				if err != nil {
-					return err
+					other, ok := index[ingred.ID]
+					if !ok {
+						return err
+					}
+					name = other.Name
 				}
 				ys.Ingredients[i].ID = name
 			}
@@ -316,3 +411,452 @@ func ingredientName(id string, ingreds []Ingredient) (string, error) {
 	}
 	return "", errors.New(fmt.Sprintf("id %s not found in ingredients list", id))
 }
+
+// HasAllergen reports whether the recipe contains the named allergen.
+// Both the recipe-level allergens and the allergens of each ingredient
+// are checked. Ingredient allergen IDs are resolved against the
+// recipe-level allergens, which Recipes.ResolveAllergens completes; IDs
+// that cannot be resolved are compared to name directly.
+func (r Recipe) HasAllergen(name string) bool {
+	for _, a := range r.Allergens {
+		if a.matches(name) {
+			return true
+		}
+	}
+	for _, ingred := range r.Ingredients {
+		for _, id := range ingred.Allergens {
+			if a, ok := r.allergen(id); ok {
+				if a.matches(name) {
+					return true
+				}
+			} else if strings.EqualFold(id, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResolveAllergens adds to the recipe-level allergens of each recipe the
+// allergens of its ingredients that it lacks, looking their IDs up among
+// the allergens of all the recipes, since Hello Fresh often lists an
+// ingredient's allergen only on other recipes. It should be called
+// before the recipes are filtered.
+func (rs Recipes) ResolveAllergens() {
+	index := make(map[string]Allergen)
+	for _, r := range rs {
+		for _, a := range r.Allergens {
+			index[a.ID] = a
+		}
+	}
+	for i := range rs {
+		for _, ingred := range rs[i].Ingredients {
+			for _, id := range ingred.Allergens {
+				if _, ok := rs[i].allergen(id); ok {
+					continue
+				}
+				if a, ok := index[id]; ok {
+					rs[i].Allergens = append(rs[i].Allergens, a)
+				}
+			}
+		}
+	}
+}
+
+// allergen resolves an allergen ID against the recipe-level allergens.
+func (r Recipe) allergen(id string) (Allergen, bool) {
+	for _, a := range r.Allergens {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Allergen{}, false
+}
+
+func (a Allergen) matches(name string) bool {
+	return a.ID == name || strings.EqualFold(a.Name, name) || strings.EqualFold(a.Slug, name)
+}
+
+// IsFreeOf reports whether none of the named allergens appear in the
+// recipe-level or ingredient-level allergen data.
+func (r Recipe) IsFreeOf(allergens ...string) bool {
+	return !r.hasAnyAllergen(allergens)
+}
+
+// CertifyFreeOf maps the ID of each recipe to whether it is free of all
+// the named allergens.
+func (rs Recipes) CertifyFreeOf(allergens ...string) map[string]bool {
+	free := make(map[string]bool, len(rs))
+	for _, r := range rs {
+		free[r.ID] = r.IsFreeOf(allergens...)
+	}
+	return free
+}
+
+// ExcludeAllergens returns the recipes that contain none of the named
+// allergens, as reported by HasAllergen.
+func (rs Recipes) ExcludeAllergens(names ...string) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if !r.hasAnyAllergen(names) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// ExcludeIDs returns the recipes whose IDs are not among ids.
+func (rs Recipes) ExcludeIDs(ids ...string) Recipes {
+	exclude := make(map[string]bool)
+	for _, id := range ids {
+		exclude[id] = true
+	}
+	var kept Recipes
+	for _, r := range rs {
+		if !exclude[r.ID] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// countryLanguages maps Hello Fresh country codes to the ISO 639-1 code
+// of the language their recipes are published in.
+var countryLanguages = map[string]string{
+	"AT": "de",
+	"AU": "en",
+	"BE": "nl",
+	"CA": "en",
+	"CH": "de",
+	"DE": "de",
+	"DK": "da",
+	"ES": "es",
+	"FR": "fr",
+	"GB": "en",
+	"IE": "en",
+	"IT": "it",
+	"LU": "fr",
+	"NL": "nl",
+	"NO": "no",
+	"NZ": "en",
+	"SE": "sv",
+	"US": "en",
+}
+
+// Language returns the ISO 639-1 code of the language the recipe is
+// written in, inferred from its country. It returns the empty string if
+// the country is unknown.
+func (r Recipe) Language() string {
+	return countryLanguages[strings.ToUpper(r.Country)]
+}
+
+// FilterByLanguage returns the recipes written in any of the given
+// languages.
+func (rs Recipes) FilterByLanguage(langs ...string) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		lang := r.Language()
+		for _, l := range langs {
+			if lang != "" && strings.EqualFold(lang, l) {
+				kept = append(kept, r)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// BoxOnly returns the recipes whose ingredients are all shipped in the
+// box, so that no pantry items are needed.
+func (rs Recipes) BoxOnly() Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.boxOnly() {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (r Recipe) boxOnly() bool {
+	for _, ingred := range r.Ingredients {
+		if !ingred.Shipped {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByMinRating returns the recipes with a rating of at least min.
+func (rs Recipes) FilterByMinRating(min float64) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.Rating >= min {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// FilterByMaxUtensils returns the recipes requiring at most n utensils.
+func (rs Recipes) FilterByMaxUtensils(n int) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if len(r.Utensils) <= n {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// StepCount returns the number of cooking steps of the recipe, or 0 if
+// the recipe has no step data.
+func (r Recipe) StepCount() int {
+	return len(r.Steps)
+}
+
+// FilterByMaxSteps returns the recipes with at most n cooking steps.
+func (rs Recipes) FilterByMaxSteps(n int) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.StepCount() <= n {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// Changed returns the recipes that are not in manifest, which maps
+// recipe IDs to their last-seen UpdatedAt, or whose UpdatedAt differs
+// from the one recorded there.
+func (rs Recipes) Changed(manifest map[string]time.Time) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if t, ok := manifest[r.ID]; !ok || !r.UpdatedAt.Equal(t) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// spicyIndicators are the tag slugs, tag names, and tag preferences that
+// mark a recipe as spicy.
+var spicyIndicators = []string{"spicy", "spicy-food", "extra-spicy", "hot"}
+
+// IsSpicy reports whether any of the recipe's tags or tag preferences
+// mark it as spicy.
+func (r Recipe) IsSpicy() bool {
+	for _, t := range r.Tags {
+		if isSpicyIndicator(t.Slug) || isSpicyIndicator(t.Name) {
+			return true
+		}
+		for _, p := range t.Preferences {
+			if isSpicyIndicator(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isSpicyIndicator(s string) bool {
+	for _, ind := range spicyIndicators {
+		if strings.EqualFold(s, ind) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeSpicy returns the recipes that are not spicy.
+func (rs Recipes) ExcludeSpicy() Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if !r.IsSpicy() {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// FilterByCuisine returns the recipes belonging to any of the named
+// cuisines.
+func (rs Recipes) FilterByCuisine(names ...string) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.hasAnyCuisine(names) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// FilterByCategory returns the recipes belonging to any of the named
+// categories.
+func (rs Recipes) FilterByCategory(names ...string) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.hasAnyCategory(names) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (r Recipe) hasAnyCategory(names []string) bool {
+	for _, c := range r.Categories {
+		for _, name := range names {
+			if strings.EqualFold(c.Name, name) || strings.EqualFold(c.Slug, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r Recipe) hasAnyCuisine(names []string) bool {
+	for _, c := range r.Cuisines {
+		for _, name := range names {
+			if strings.EqualFold(c.Name, name) || strings.EqualFold(c.Slug, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r Recipe) hasAnyAllergen(names []string) bool {
+	for _, name := range names {
+		if r.HasAllergen(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllergenSummary maps the name of each allergen in the recipes to the
+// number of recipes containing it. Like HasAllergen, it considers both
+// recipe-level and ingredient-level allergens; ingredient allergen IDs
+// that cannot be resolved are counted by ID.
+func (rs Recipes) AllergenSummary() map[string]int {
+	summary := make(map[string]int)
+	for _, r := range rs {
+		for name := range r.allergenNames() {
+			summary[name]++
+		}
+	}
+	return summary
+}
+
+// allergenNames returns the set of names of the allergens in the recipe,
+// resolving ingredient allergen IDs as HasAllergen does.
+func (r Recipe) allergenNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, a := range r.Allergens {
+		names[a.Name] = true
+	}
+	for _, ingred := range r.Ingredients {
+		for _, id := range ingred.Allergens {
+			if a, ok := r.allergen(id); ok {
+				names[a.Name] = true
+			} else {
+				names[id] = true
+			}
+		}
+	}
+	return names
+}
+
+// IngredientFamilies returns the distinct ingredient families used by
+// the recipes, sorted by priority and then by name.
+func (rs Recipes) IngredientFamilies() []IngredientFamily {
+	seen := make(map[string]bool)
+	var fs []IngredientFamily
+	for _, r := range rs {
+		for _, ingred := range r.Ingredients {
+			f := ingred.Family
+			if f.ID == "" || seen[f.ID] {
+				continue
+			}
+			seen[f.ID] = true
+			fs = append(fs, f)
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		if fs[i].Priority != fs[j].Priority {
+			return fs[i].Priority < fs[j].Priority
+		}
+		return fs[i].Name < fs[j].Name
+	})
+	return fs
+}
+
+// DedupByName collapses recipes whose names match, ignoring case and
+// surrounding whitespace. Of each set of duplicates, the recipe with the
+// most complete data is kept in the position of the first.
+func (rs Recipes) DedupByName() Recipes {
+	index := make(map[string]int)
+	var kept Recipes
+	for _, r := range rs {
+		name := strings.ToLower(strings.TrimSpace(r.Name))
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(kept)
+			kept = append(kept, r)
+			continue
+		}
+		if r.completeness() > kept[i].completeness() {
+			kept[i] = r
+		}
+	}
+	return kept
+}
+
+// completeness scores how much data the recipe carries: the number of
+// non-zero fields plus the number of elements in each list field.
+func (r Recipe) completeness() int {
+	n := 0
+	v := reflect.ValueOf(r)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.IsZero() {
+			n++
+		}
+		if f.Kind() == reflect.Slice {
+			n += f.Len()
+		}
+	}
+	return n
+}
+
+// AvailableIn returns the recipes whose ingredients are all used in the
+// given country, approximating which recipes can be shipped there. An
+// ingredient is considered used if its family has non-zero usage in the
+// country. Ingredients without family data are ignored.
+func (rs Recipes) AvailableIn(country string) Recipes {
+	var kept Recipes
+	for _, r := range rs {
+		if r.availableIn(country) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (r Recipe) availableIn(country string) bool {
+	for _, ingred := range r.Ingredients {
+		if ingred.Family.ID != "" && ingred.Family.usage(country) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f IngredientFamily) usage(country string) int {
+	for c, n := range f.UsageByCountry {
+		if strings.EqualFold(c, country) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,432 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// milkRecipes returns a recipe declaring the milk allergen and a recipe
+// whose only reference to it is an ingredient-level ID.
+func milkRecipes() Recipes {
+	milk := Allergen{ID: "al9", Name: "Milk", Slug: "milk"}
+	return Recipes{
+		{ID: "declared", Allergens: []Allergen{milk}, Ingredients: []Ingredient{{ID: "i1", Allergens: []string{"al9"}}}},
+		{ID: "ingredient-only", Ingredients: []Ingredient{{ID: "i2", Allergens: []string{"al9"}}}},
+		{ID: "free", Ingredients: []Ingredient{{ID: "i3"}}},
+	}
+}
+
+func TestHasAllergenIngredientOnly(t *testing.T) {
+	rs := milkRecipes()
+	if rs[1].HasAllergen("milk") {
+		t.Error("HasAllergen(milk) before ResolveAllergens = true, want false")
+	}
+	if !rs[1].HasAllergen("al9") {
+		t.Error("HasAllergen(al9) = false, want true for an unresolved ID")
+	}
+	rs.ResolveAllergens()
+	if !rs[1].HasAllergen("milk") {
+		t.Error("HasAllergen(milk) after ResolveAllergens = false, want true")
+	}
+}
+
+func TestResolveAllergens(t *testing.T) {
+	rs := milkRecipes()
+	rs.ResolveAllergens()
+	want := [][]Allergen{
+		{{ID: "al9", Name: "Milk", Slug: "milk"}},
+		{{ID: "al9", Name: "Milk", Slug: "milk"}},
+		nil,
+	}
+	for i, r := range rs {
+		if !reflect.DeepEqual(r.Allergens, want[i]) {
+			t.Errorf("recipe %s: Allergens = %v, want %v", r.ID, r.Allergens, want[i])
+		}
+	}
+}
+
+func TestExcludeAllergens(t *testing.T) {
+	rs := milkRecipes()
+	if got, want := ids(rs.ExcludeAllergens("Milk")), []string{"ingredient-only", "free"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExcludeAllergens(Milk) before ResolveAllergens = %v, want %v", got, want)
+	}
+	rs.ResolveAllergens()
+	if got, want := ids(rs.ExcludeAllergens("Milk")), []string{"free"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExcludeAllergens(Milk) = %v, want %v", got, want)
+	}
+}
+
+func TestCertifyFreeOf(t *testing.T) {
+	rs := milkRecipes()
+	rs.ResolveAllergens()
+	want := map[string]bool{"declared": false, "ingredient-only": false, "free": true}
+	if got := rs.CertifyFreeOf("milk"); !reflect.DeepEqual(got, want) {
+		t.Errorf("CertifyFreeOf(milk) = %v, want %v", got, want)
+	}
+	// Resolved allergens remain once the recipe declaring them has been
+	// filtered out.
+	want = map[string]bool{"ingredient-only": false, "free": true}
+	if got := rs[1:].CertifyFreeOf("Milk"); !reflect.DeepEqual(got, want) {
+		t.Errorf("CertifyFreeOf(Milk) without the declaring recipe = %v, want %v", got, want)
+	}
+}
+
+func TestAllergenSummary(t *testing.T) {
+	rs := milkRecipes()
+	want := map[string]int{"Milk": 1, "al9": 1}
+	if got := rs.AllergenSummary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllergenSummary() before ResolveAllergens = %v, want %v", got, want)
+	}
+	rs.ResolveAllergens()
+	want = map[string]int{"Milk": 2}
+	if got := rs.AllergenSummary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllergenSummary() = %v, want %v", got, want)
+	}
+	if got := rs[1:].AllergenSummary(); !reflect.DeepEqual(got, map[string]int{"Milk": 1}) {
+		t.Errorf("AllergenSummary() without the declaring recipe = %v, want map[Milk:1]", got)
+	}
+}
+
+func ids(rs Recipes) []string {
+	s := make([]string, len(rs))
+	for i, r := range rs {
+		s[i] = r.ID
+	}
+	return s
+}
+
+func TestIngredientFamilies(t *testing.T) {
+	veg := IngredientFamily{ID: "f1", Name: "Vegetables", Priority: 2}
+	grains := IngredientFamily{ID: "f2", Name: "Grains", Priority: 2}
+	meat := IngredientFamily{ID: "f3", Name: "Meat", Priority: 1}
+	rs := Recipes{
+		{Ingredients: []Ingredient{{ID: "i1", Family: veg}, {ID: "i2", Family: meat}, {ID: "i3"}}},
+		{Ingredients: []Ingredient{{ID: "i4", Family: veg}, {ID: "i5", Family: grains}}},
+	}
+	var got []string
+	for _, f := range rs.IngredientFamilies() {
+		got = append(got, f.Name)
+	}
+	want := []string{"Meat", "Grains", "Vegetables"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IngredientFamilies() = %v, want %v", got, want)
+	}
+}
+
+const testSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset>
+	<url><loc>https://www.hellofresh.com/recipes/quick-meals</loc><lastmod>2023-05-01</lastmod><changefreq>weekly</changefreq><priority>0.8</priority></url>
+	<url><loc>https://www.hellofresh.com/recipes/vegan-recipes</loc></url>
+</urlset>`
+
+func TestCollectionURLs(t *testing.T) {
+	srv := servePages(t, map[string]string{"/sitemap.xml": testSitemap})
+	us, err := CollectionURLs(srv.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(us) != 2 {
+		t.Fatalf("got %d URLs, want 2", len(us))
+	}
+	u := us[0]
+	if u.LOC != "https://www.hellofresh.com/recipes/quick-meals" || u.LastMod != "2023-05-01" || u.ChangeFreq != "weekly" || u.Priority != 0.8 {
+		t.Errorf("URL 0 = %+v", u)
+	}
+	if us[1].Priority != 0 {
+		t.Errorf("URL 1 priority = %v, want 0", us[1].Priority)
+	}
+}
+
+func TestCollectionURLsInvalid(t *testing.T) {
+	for _, sitemap := range []string{
+		"",
+		"sitemap.xml",
+		"/local/sitemap.xml",
+		"ftp://example.com/sitemap.xml",
+		"https:///sitemap.xml",
+		"::not a url",
+	} {
+		if _, err := CollectionURLs(sitemap); err == nil {
+			t.Errorf("CollectionURLs(%q) succeeded, want error", sitemap)
+		}
+	}
+}
+
+func TestDedupByName(t *testing.T) {
+	rs := Recipes{
+		{ID: "sparse", Name: "Chicken Tacos"},
+		{ID: "other", Name: "Beef Stew"},
+		{ID: "full", Name: " chicken tacos ", Headline: "with salsa", Ingredients: []Ingredient{{ID: "i1"}, {ID: "i2"}}},
+		{ID: "sparser", Name: "CHICKEN TACOS"},
+	}
+	want := []string{"full", "other"}
+	if got := ids(rs.DedupByName()); !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupByName() = %v, want %v", got, want)
+	}
+}
+
+func TestAvailableIn(t *testing.T) {
+	usedIn := func(countries ...string) IngredientFamily {
+		f := IngredientFamily{ID: "f" + strings.Join(countries, ""), UsageByCountry: map[string]int{}}
+		for _, c := range countries {
+			f.UsageByCountry[c] = 10
+		}
+		return f
+	}
+	rs := Recipes{
+		{ID: "everywhere", Ingredients: []Ingredient{{Family: usedIn("US", "DE")}, {}}},
+		{ID: "us-only", Ingredients: []Ingredient{{Family: usedIn("US", "DE")}, {Family: usedIn("US")}}},
+	}
+	if got := ids(rs.AvailableIn("de")); !reflect.DeepEqual(got, []string{"everywhere"}) {
+		t.Errorf("AvailableIn(de) = %v, want [everywhere]", got)
+	}
+	if got := ids(rs.AvailableIn("US")); len(got) != 2 {
+		t.Errorf("AvailableIn(US) = %v, want both recipes", got)
+	}
+}
+
+func TestCollectionURLsNamespaced(t *testing.T) {
+	sitemaps := map[string]string{
+		"/default.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://www.hellofresh.com/recipes/quick-meals</loc><priority>0.8</priority></url>
+	<url><loc>https://www.hellofresh.com/recipes/vegan-recipes</loc><priority>0.5</priority></url>
+</urlset>`,
+		"/prefixed.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sm:urlset xmlns:sm="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sm:url><sm:loc>https://www.hellofresh.com/recipes/quick-meals</sm:loc><sm:priority>0.8</sm:priority></sm:url>
+	<sm:url><sm:loc>https://www.hellofresh.com/recipes/vegan-recipes</sm:loc><sm:priority>0.5</sm:priority></sm:url>
+</sm:urlset>`,
+	}
+	srv := servePages(t, sitemaps)
+	for path := range sitemaps {
+		us, err := CollectionURLs(srv.URL + path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if len(us) != 2 || us[0].LOC != "https://www.hellofresh.com/recipes/quick-meals" || us[1].Priority != 0.5 {
+			t.Errorf("%s: URLs = %+v", path, us)
+		}
+	}
+}
+
+func TestFilterByMinPriority(t *testing.T) {
+	us, err := CollectionURLs(servePages(t, map[string]string{"/sitemap.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<urlset>
+	<url><loc>https://www.hellofresh.com/recipes/quick-meals</loc><priority>0.8</priority></url>
+	<url><loc>https://www.hellofresh.com/recipes/vegan-recipes</loc><priority>0.5</priority></url>
+	<url><loc>https://www.hellofresh.com/recipes/easy-recipes</loc></url>
+	<url><loc>https://www.hellofresh.com/recipes/family-recipes</loc><priority>1.0</priority></url>
+</urlset>`}).URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		min  float64
+		want []string
+	}{
+		{0, []string{"quick-meals", "vegan-recipes", "easy-recipes", "family-recipes"}},
+		{0.5, []string{"quick-meals", "vegan-recipes", "family-recipes"}},
+		{0.8, []string{"quick-meals", "family-recipes"}},
+		{1, []string{"family-recipes"}},
+		{1.5, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, u := range FilterByMinPriority(us, tt.min) {
+			got = append(got, strings.TrimPrefix(u.LOC, "https://www.hellofresh.com/recipes/"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterByMinPriority(%v) = %v, want %v", tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalCategories(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		category string
+		want     []string
+	}{
+		{"list", `{"categories":[{"id":"c1","name":"Main"},{"id":"c2","name":"Quick"}]}`, "Main", []string{"Main", "Quick"}},
+		{"single", `{"category":{"id":"c1","name":"Main"}}`, "Main", []string{"Main"}},
+		{"none", `{}`, "", nil},
+	}
+	for _, tt := range tests {
+		var r Recipe
+		err := json.Unmarshal([]byte(tt.payload), &r)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, c := range r.Categories {
+			got = append(got, c.Name)
+		}
+		if r.Category.Name != tt.category || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Category = %q, Categories = %v, want %q, %v", tt.name, r.Category.Name, got, tt.category, tt.want)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		country, want string
+	}{
+		{"DE", "de"},
+		{"at", "de"},
+		{"US", "en"},
+		{"BE", "nl"},
+		{"SE", "sv"},
+		{"XX", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Recipe{Country: tt.country}).Language(); got != tt.want {
+			t.Errorf("Language() of country %q = %q, want %q", tt.country, got, tt.want)
+		}
+	}
+}
+
+func TestFilterByLanguage(t *testing.T) {
+	rs := Recipes{{ID: "de", Country: "DE"}, {ID: "us", Country: "US"}, {ID: "fr", Country: "FR"}, {ID: "unknown"}}
+	want := []string{"de", "fr"}
+	if got := ids(rs.FilterByLanguage("DE", "fr")); !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterByLanguage(DE, fr) = %v, want %v", got, want)
+	}
+}
+
+func TestFilterByMaxUtensils(t *testing.T) {
+	rs := Recipes{
+		{ID: "none"},
+		{ID: "two", Utensils: []Utensil{{ID: "u1"}, {ID: "u2"}}},
+		{ID: "four", Utensils: []Utensil{{ID: "u1"}, {ID: "u2"}, {ID: "u3"}, {ID: "u4"}}},
+	}
+	want := []string{"none", "two"}
+	if got := ids(rs.FilterByMaxUtensils(2)); !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterByMaxUtensils(2) = %v, want %v", got, want)
+	}
+	if got := ids(rs.FilterByMaxUtensils(0)); !reflect.DeepEqual(got, []string{"none"}) {
+		t.Errorf("FilterByMaxUtensils(0) = %v, want [none]", got)
+	}
+}
+
+func TestExcludeSpicy(t *testing.T) {
+	rs := Recipes{
+		{ID: "slug", Tags: []Tag{{Name: "Quick", Slug: "quick"}, {Name: "Spicy", Slug: "spicy"}}},
+		{ID: "mild", Tags: []Tag{{Name: "Family Friendly", Slug: "family-friendly"}}},
+		{ID: "preference", Tags: []Tag{{Name: "Chef's Choice", Preferences: []string{"Extra-Spicy"}}}},
+		{ID: "untagged"},
+	}
+	want := []string{"mild", "untagged"}
+	if got := ids(rs.ExcludeSpicy()); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExcludeSpicy() = %v, want %v", got, want)
+	}
+}
+
+func TestYieldIDsToNames(t *testing.T) {
+	rs := Recipes{
+		{
+			ID:          "tacos",
+			Ingredients: []Ingredient{{ID: "i1", Name: "Tortillas"}},
+			Yields:      []Yield{{Yields: 2, Ingredients: []IngredientYield{{ID: "i1"}, {ID: "sauce"}}}},
+		},
+		{
+			ID:          "burrito",
+			Ingredients: []Ingredient{{ID: "sauce", Name: "Chipotle Sauce"}},
+		},
+	}
+	err := rs.YieldIDsToNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, iy := range rs[0].Yields[0].Ingredients {
+		got = append(got, iy.ID)
+	}
+	want := []string{"Tortillas", "Chipotle Sauce"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("yield names = %v, want %v", got, want)
+	}
+	missing := Recipes{{Yields: []Yield{{Ingredients: []IngredientYield{{ID: "sauce"}}}}}}
+	if err := missing.YieldIDsToNames(); err == nil {
+		t.Error("YieldIDsToNames without any recipe listing the sauce succeeded")
+	}
+}
+
+func TestRatings(t *testing.T) {
+	var rs Recipes
+	err := json.Unmarshal([]byte(`[
+		{"id":"loved","averageRating":4.6,"ratingsCount":1200},
+		{"id":"disliked","averageRating":2.1,"ratingsCount":35},
+		{"id":"unrated"}
+	]`), &rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].Rating != 4.6 || rs[0].RatingCount != 1200 {
+		t.Errorf("loved: Rating = %v, RatingCount = %d, want 4.6, 1200", rs[0].Rating, rs[0].RatingCount)
+	}
+	if rs[2].Rating != 0 || rs[2].RatingCount != 0 {
+		t.Errorf("unrated: Rating = %v, RatingCount = %d, want 0, 0", rs[2].Rating, rs[2].RatingCount)
+	}
+	if got := ids(rs.FilterByMinRating(4)); !reflect.DeepEqual(got, []string{"loved"}) {
+		t.Errorf("FilterByMinRating(4) = %v, want [loved]", got)
+	}
+}
+
+func TestAbsoluteLinks(t *testing.T) {
+	r := Recipe{
+		Link:      "/recipes/chicken-tacos-64a1",
+		ImagePath: "/image/upload/hellofresh_s3/image/chicken-tacos.jpg",
+		VideoLink: "https://videos.example.com/tacos.mp4",
+	}
+	err := r.AbsoluteLinks("https://www.hellofresh.com/recipes/quick-meals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://www.hellofresh.com/recipes/chicken-tacos-64a1"; r.Link != want {
+		t.Errorf("Link = %q, want %q", r.Link, want)
+	}
+	if want := "https://www.hellofresh.com/image/upload/hellofresh_s3/image/chicken-tacos.jpg"; r.ImageLink != want {
+		t.Errorf("ImageLink = %q, want %q", r.ImageLink, want)
+	}
+	if want := "https://videos.example.com/tacos.mp4"; r.VideoLink != want {
+		t.Errorf("VideoLink = %q, want %q", r.VideoLink, want)
+	}
+	if r.CardLink != "" {
+		t.Errorf("CardLink = %q, want empty", r.CardLink)
+	}
+}
+
+func TestBoxOnly(t *testing.T) {
+	rs := Recipes{
+		{ID: "pantry", Ingredients: []Ingredient{{ID: "i1", Shipped: true}, {ID: "oil", Name: "Olive Oil"}}},
+		{ID: "shipped", Ingredients: []Ingredient{{ID: "i1", Shipped: true}, {ID: "i2", Shipped: true}}},
+	}
+	if got := ids(rs.BoxOnly()); !reflect.DeepEqual(got, []string{"shipped"}) {
+		t.Errorf("BoxOnly() = %v, want [shipped]", got)
+	}
+}
+
+func TestFilterByMaxSteps(t *testing.T) {
+	rs := Recipes{
+		{ID: "quick", Steps: []Step{{Index: 1}, {Index: 2}}},
+		{ID: "involved", Steps: []Step{{Index: 1}, {Index: 2}, {Index: 3}, {Index: 4}, {Index: 5}, {Index: 6}}},
+		{ID: "no-steps"},
+	}
+	if n := rs[1].StepCount(); n != 6 {
+		t.Errorf("StepCount() = %d, want 6", n)
+	}
+	want := []string{"quick", "no-steps"}
+	if got := ids(rs.FilterByMaxSteps(4)); !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterByMaxSteps(4) = %v, want %v", got, want)
+	}
+}
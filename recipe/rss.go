@@ -0,0 +1,74 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// WriteRSS writes the recipes to w as an RSS 2.0 feed with one item
+// per recipe.
+func (rs Recipes) WriteRSS(w io.Writer, channelTitle, link string) error {
+	feed := rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       channelTitle,
+			Link:        link,
+			Description: channelTitle,
+		},
+	}
+	for _, r := range rs {
+		item := rssItem{
+			Title:       r.Name,
+			Link:        r.Link,
+			Description: r.Description,
+			GUID:        rssGUID{ID: r.ID},
+		}
+		if !r.CreatedAt.IsZero() {
+			item.PubDate = r.CreatedAt.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	err = enc.Encode(feed)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestWriteRSS(t *testing.T) {
+	rs := Recipes{
+		{ID: "a", Name: "Tacos", Link: "https://www.hellofresh.com/recipes/tacos-a", CreatedAt: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "b", Name: "Curry & Rice"},
+	}
+	var buf bytes.Buffer
+	err := rs.WriteRSS(&buf, "Hello Fresh recipes", "https://www.hellofresh.com/recipes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed rss
+	err = xml.Unmarshal(buf.Bytes(), &feed)
+	if err != nil {
+		t.Fatalf("parsing feed: %v", err)
+	}
+	if feed.Version != "2.0" {
+		t.Errorf("version = %q, want 2.0", feed.Version)
+	}
+	items := feed.Channel.Items
+	if len(items) != len(rs) {
+		t.Fatalf("got %d items, want %d", len(items), len(rs))
+	}
+	if items[1].Title != "Curry & Rice" || items[1].GUID.ID != "b" {
+		t.Errorf("item 1 = %+v, want title Curry & Rice and guid b", items[1])
+	}
+	if items[0].PubDate == "" || items[1].PubDate != "" {
+		t.Errorf("pubDates = %q, %q, want only the first set", items[0].PubDate, items[1].PubDate)
+	}
+}
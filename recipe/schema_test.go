@@ -0,0 +1,45 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	b, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Type       string
+		Properties map[string]struct {
+			Type  any
+			Items struct {
+				Type       string
+				Properties map[string]any
+			}
+		}
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type != "object" {
+		t.Errorf("type = %q, want object", s.Type)
+	}
+	if typ := s.Properties["Name"].Type; typ != "string" {
+		t.Errorf("Name type = %v, want string", typ)
+	}
+	ingreds := s.Properties["Ingredients"]
+	types, _ := ingreds.Type.([]any)
+	if len(types) == 0 || types[0] != "array" {
+		t.Errorf("Ingredients type = %v, want array", ingreds.Type)
+	}
+	if ingreds.Items.Type != "object" || ingreds.Items.Properties["Name"] == nil {
+		t.Errorf("Ingredients items = %+v, want objects with a Name", ingreds.Items)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A Scraper scrapes recipes from pages of the Hello Fresh website. Its
+// zero value is a usable scraper.
+type Scraper struct {
+	// Client is the HTTP client used to fetch pages. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// OnPage, if non-nil, is called with the metrics of each page
+	// scraped, whether or not scraping it succeeded.
+	OnPage func(PageMetrics)
+
+	// Retries is the number of times the fetch of a page is retried
+	// when its response is truncated before the payload could be read.
+	Retries int
+}
+
+// PageMetrics describe the scrape of a single page.
+type PageMetrics struct {
+	Page    string
+	Fetch   time.Duration // time until the response headers arrived
+	Parse   time.Duration // time to read and decode the payload
+	Recipes int           // number of recipes found
+	Err     error
+}
+
+// pageSizes are the page sizes the Hello Fresh website commonly caps
+// recipe listings at.
+var pageSizes = []int{10, 20, 25, 50, 100}
+
+// MayBePaginated reports whether the number of recipes found on the page
+// equals a common page size, which suggests the listing was capped at
+// one page and further pages were not followed.
+func (m PageMetrics) MayBePaginated() bool {
+	for _, n := range pageSizes {
+		if m.Recipes == n {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultScraper is the Scraper used by ScrapeRecipes, ScrapeRaw,
+// ScrapeCollection, ScrapeByIDs, and Search.
+var DefaultScraper = &Scraper{}
+
+// ScrapeRecipes scrapes recipes from the JSON payload on the
+// Hello Fresh website.
+func (s *Scraper) ScrapeRecipes(page string) (Recipes, error) {
+	return s.scrape(page, nil)
+}
+
+// ScrapeRaw is like ScrapeRecipes but also returns the raw data of each
+// query in the payload, which helps diagnose payload shapes that are not
+// mapped to recipes.
+func (s *Scraper) ScrapeRaw(page string) (Recipes, []json.RawMessage, error) {
+	var raw []json.RawMessage
+	rs, err := s.scrape(page, func(data json.RawMessage) {
+		raw = append(raw, data)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rs, raw, nil
+}
+
+// ScrapeCollection scrapes recipes from each of the provided pages.
+// Pages that yield no recipes, such as category or landing pages, are
+// skipped instead of failing the whole scrape. The number of skipped
+// pages is returned alongside the recipes. Pages that cannot be fetched,
+// including those with an error status, fail the scrape.
+func (s *Scraper) ScrapeCollection(pages []string) (Recipes, int, error) {
+	var rs Recipes
+	skipped := 0
+	for _, page := range pages {
+		prs, err := s.ScrapeRecipes(page)
+		if errors.Is(err, errNoRecipeProps) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(prs) == 0 {
+			skipped++
+			continue
+		}
+		rs = append(rs, prs...)
+	}
+	return rs, skipped, nil
+}
+
+// An UnresolvedIDsError lists the recipe IDs ScrapeByIDs could not
+// resolve to a recipe.
+type UnresolvedIDsError struct {
+	IDs []string
+}
+
+func (e *UnresolvedIDsError) Error() string {
+	return fmt.Sprintf("unresolved recipe IDs: %s", strings.Join(e.IDs, ", "))
+}
+
+// ScrapeByIDs scrapes the recipes with the given IDs from the Hello Fresh
+// website at domain, such as www.hellofresh.com. Each ID is resolved
+// through its recipe page. If some IDs do not resolve to a recipe,
+// because their page is not found or does not list them, the recipes
+// that did are returned with an *UnresolvedIDsError listing the rest.
+func (s *Scraper) ScrapeByIDs(domain string, ids []string) (Recipes, error) {
+	var (
+		rs         Recipes
+		unresolved []string
+	)
+	for _, id := range ids {
+		u := url.URL{Scheme: "https", Host: domain, Path: "/recipes/" + url.PathEscape(id)}
+		prs, err := s.ScrapeRecipes(u.String())
+		if err != nil && !errors.Is(err, errNoRecipeProps) && !isNotFound(err) {
+			return nil, err
+		}
+		found := false
+		for _, r := range prs {
+			if r.ID == id {
+				rs = append(rs, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			unresolved = append(unresolved, id)
+		}
+	}
+	if len(unresolved) > 0 {
+		return rs, &UnresolvedIDsError{IDs: unresolved}
+	}
+	return rs, nil
+}
+
+// Search returns the recipes matching query using the search of the Hello
+// Fresh website at domain, such as www.hellofresh.com. A search without
+// matches returns no recipes and no error.
+func (s *Scraper) Search(domain, query string) (Recipes, error) {
+	u := url.URL{
+		Scheme:   "https",
+		Host:     domain,
+		Path:     "/recipes/search",
+		RawQuery: url.Values{"q": {query}}.Encode(),
+	}
+	rs, err := s.ScrapeRecipes(u.String())
+	if errors.Is(err, errNoRecipeProps) {
+		return nil, nil
+	}
+	return rs, err
+}
+
+func (s *Scraper) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// scrape scrapes recipes from page, calling fn, if non-nil, with the raw
+// data of each payload query. Relative recipe links are resolved against
+// page. Truncated responses are fetched again up to s.Retries times.
+func (s *Scraper) scrape(page string, fn func(json.RawMessage)) (rs Recipes, err error) {
+	m := PageMetrics{Page: page}
+	if s.OnPage != nil {
+		defer func() {
+			m.Recipes = len(rs)
+			m.Err = err
+			s.OnPage(m)
+		}()
+	}
+	var raw []json.RawMessage
+	for attempt := 0; ; attempt++ {
+		var truncated bool
+		rs, raw, truncated, err = s.fetch(page, fn != nil, &m)
+		if !truncated || attempt >= s.Retries {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fn != nil {
+		for _, data := range raw {
+			fn(data)
+		}
+	}
+	for i := range rs {
+		err = rs[i].AbsoluteLinks(page)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// fetch fetches page once and parses its recipes, and if wantRaw is set
+// the raw data of its payload queries, recording the timings in m. Raw
+// data is only kept when wanted, since otherwise recipes are decoded one
+// at a time without holding whole queries in memory. A response with a
+// status other than 2xx is an error. If parsing fails,
+// fetch reports whether the response was truncated, that is, whether
+// fewer bytes were read than its Content-Length announced or the
+// connection closed mid-body.
+func (s *Scraper) fetch(page string, wantRaw bool, m *PageMetrics) (rs Recipes, raw []json.RawMessage, truncated bool, err error) {
+	start := time.Now()
+	resp, err := s.client().Get(page)
+	m.Fetch = time.Since(start)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, false, &statusError{page: page, code: resp.StatusCode, status: resp.Status}
+	}
+	body := &countingReader{r: resp.Body}
+	err = parseQueries(body, func(dec *json.Decoder) error {
+		if !wantRaw {
+			qrs, err := decodeRecipes(dec)
+			rs = append(rs, qrs...)
+			return err
+		}
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, data)
+		qrs, err := queryRecipes(data)
+		rs = append(rs, qrs...)
+		return err
+	})
+	m.Parse = time.Since(start) - m.Fetch
+	if err != nil {
+		_, derr := io.Copy(io.Discard, body)
+		truncated = errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(derr, io.ErrUnexpectedEOF) ||
+			resp.ContentLength >= 0 && body.n < resp.ContentLength
+		if truncated {
+			err = fmt.Errorf("%s: response truncated after %d bytes: %w", page, body.n, err)
+		}
+		return nil, nil, truncated, err
+	}
+	return rs, raw, false, nil
+}
+
+// A statusError reports a response whose status is not 2xx.
+type statusError struct {
+	page   string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("fetching %s: %s", e.page, e.status)
+}
+
+// isNotFound reports whether err is a 404 Not Found response.
+func isNotFound(err error) bool {
+	var serr *statusError
+	return errors.As(err, &serr) && serr.code == http.StatusNotFound
+}
+
+// A countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// payloadPage returns an HTML page whose recipe props payload holds a
+// query with each of the given data.
+func payloadPage(data ...string) string {
+	qs := make([]string, len(data))
+	for i, d := range data {
+		qs[i] = `{"state":{"data":` + d + `}}`
+	}
+	return `<html><head><script id="__NEXT_DATA__" type="application/json">` +
+		`{"props":{"pageProps":{"ssrPayload":{"dehydratedState":{"queries":[` +
+		strings.Join(qs, ",") + `]}}}}}</script></head><body></body></html>`
+}
+
+// itemsData returns query data listing recipes with the given IDs.
+func itemsData(ids ...string) string {
+	items := make([]string, len(ids))
+	for i, id := range ids {
+		items[i] = fmt.Sprintf(`{"id":%q,"name":"Recipe %s"}`, id, id)
+	}
+	return `{"items":[` + strings.Join(items, ",") + `]}`
+}
+
+// servePages starts a server serving the given pages by path.
+func servePages(t testing.TB, pages map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(pagesHandler(pages))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serveTLSPages is like servePages but serves the pages over HTTPS, as
+// the Hello Fresh website does.
+func serveTLSPages(t testing.TB, pages map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(pagesHandler(pages))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func pagesHandler(pages map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, page)
+	})
+}
+
+func TestScrapeCollection(t *testing.T) {
+	srv := servePages(t, map[string]string{
+		"/empty":   `<html><body><h1>Categories</h1></body></html>`,
+		"/recipes": payloadPage(itemsData("a", "b")),
+	})
+	s := &Scraper{Client: srv.Client()}
+	rs, skipped, err := s.ScrapeCollection([]string{srv.URL + "/empty", srv.URL + "/recipes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+	if got := ids(rs); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("recipes = %v, want [a b]", got)
+	}
+}
+
+func TestScrapeCollectionErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/recipes" {
+			fmt.Fprint(w, payloadPage(itemsData("a")))
+			return
+		}
+		http.Error(w, "<html><body>Internal Server Error</body></html>", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	s := &Scraper{Client: srv.Client()}
+	_, skipped, err := s.ScrapeCollection([]string{srv.URL + "/recipes", srv.URL + "/broken"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("err = %v, want a 500 status error", err)
+	}
+	if skipped != 0 {
+		t.Errorf("skipped = %d, want 0", skipped)
+	}
+}
+
+func TestScrapeRaw(t *testing.T) {
+	srv := servePages(t, map[string]string{
+		"/recipes": payloadPage(`{"locale":"en-US"}`, itemsData("a"), `[1,2,3]`),
+	})
+	s := &Scraper{Client: srv.Client()}
+	rs, raw, err := s.ScrapeRaw(srv.URL + "/recipes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 {
+		t.Errorf("got %d recipes, want 1", len(rs))
+	}
+	if len(raw) != 3 {
+		t.Fatalf("got %d raw queries, want 3", len(raw))
+	}
+	for i, data := range raw {
+		if !json.Valid(data) {
+			t.Errorf("raw query %d is not valid JSON: %s", i, data)
+		}
+	}
+	var locale struct{ Locale string }
+	err = json.Unmarshal(raw[0], &locale)
+	if err != nil || locale.Locale != "en-US" {
+		t.Errorf("raw query 0 = %s, want the locale query", raw[0])
+	}
+}
+
+func TestScrapeByIDs(t *testing.T) {
+	srv := serveTLSPages(t, map[string]string{
+		"/recipes/64aBc1": payloadPage(itemsData("64aBc1")),
+		"/recipes/64DeF2": payloadPage(itemsData("64DeF2")),
+		"/recipes/64cAfe": payloadPage(itemsData("other")),
+	})
+	s := &Scraper{Client: srv.Client()}
+	rs, err := s.ScrapeByIDs(strings.TrimPrefix(srv.URL, "https://"), []string{"64aBc1", "64DeF2", "64xYz3", "64cAfe"})
+	if got, want := ids(rs), []string{"64aBc1", "64DeF2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resolved %v, want %v", got, want)
+	}
+	var unresolved *UnresolvedIDsError
+	if !errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.IDs, []string{"64xYz3", "64cAfe"}) {
+		t.Errorf("err = %v, want 64xYz3 and 64cAfe unresolved", err)
+	}
+}
+
+func TestScrapeByIDsErrorStatus(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	s := &Scraper{Client: srv.Client()}
+	_, err := s.ScrapeByIDs(strings.TrimPrefix(srv.URL, "https://"), []string{"64aBc1"})
+	var unresolved *UnresolvedIDsError
+	if err == nil || errors.As(err, &unresolved) || !strings.Contains(err.Error(), "500") {
+		t.Errorf("err = %v, want a 500 status error", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recipes/search" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("q") {
+		case "chicken tacos":
+			fmt.Fprint(w, payloadPage(itemsData("a", "b")))
+		case "nothing":
+			fmt.Fprint(w, payloadPage(`{"items":[]}`))
+		case "error":
+			http.Error(w, "search is down", http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `<html><body><p>No results</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+	s := &Scraper{Client: srv.Client()}
+	domain := strings.TrimPrefix(srv.URL, "https://")
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"chicken tacos", []string{"a", "b"}},
+		{"nothing", []string{}},
+		{"no payload", []string{}},
+	}
+	for _, tt := range tests {
+		rs, err := s.Search(domain, tt.query)
+		if err != nil {
+			t.Errorf("Search(%q): %v", tt.query, err)
+			continue
+		}
+		if got := ids(rs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if _, err := s.Search(domain, "error"); err == nil {
+		t.Error(`Search("error") with status 500 succeeded, want error`)
+	}
+}
+
+func TestScrapeRetriesTruncated(t *testing.T) {
+	page := payloadPage(itemsData("a", "b"))
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		if requests == 1 {
+			// Cut the response off in the middle of the payload.
+			fmt.Fprint(w, page[:len(page)-60])
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+	var ms []PageMetrics
+	s := &Scraper{Client: srv.Client(), Retries: 2, OnPage: func(m PageMetrics) { ms = append(ms, m) }}
+	rs, err := s.ScrapeRecipes(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(rs); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("recipes = %v, want [a b]", got)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+	if len(ms) != 1 || ms[0].Err != nil || ms[0].Recipes != 2 {
+		t.Errorf("page metrics = %+v, want one successful page of 2 recipes", ms)
+	}
+
+	requests = 0
+	s.Retries = 0
+	_, err = s.ScrapeRecipes(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("err = %v without retries, want a truncation error", err)
+	}
+}
+
+func TestScrapeDoesNotRetryComplete(t *testing.T) {
+	pages := map[string]string{
+		"/empty":      payloadPage(`{"items":[]}`),
+		"/no-payload": `<html><body><h1>Categories</h1></body></html>`,
+	}
+	for path, page := range pages {
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprint(w, page)
+		}))
+		s := &Scraper{Client: srv.Client(), Retries: 3}
+		rs, err := s.ScrapeRecipes(srv.URL + path)
+		srv.Close()
+		if len(rs) != 0 {
+			t.Errorf("%s: got recipes %v, want none", path, ids(rs))
+		}
+		if path == "/empty" && err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		if requests != 1 {
+			t.Errorf("%s: got %d requests, want 1", path, requests)
+		}
+	}
+}
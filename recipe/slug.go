@@ -0,0 +1,87 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CleanSlug returns a canonical form of the recipe slug suitable for
+// filenames and URLs. The slug is sanitized as by SanitizeSlug and
+// trailing numeric or hexadecimal IDs are removed.
+func (r Recipe) CleanSlug() string {
+	words := slugWords(r.Slug)
+	for len(words) > 1 && isSlugID(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, "-")
+}
+
+// SanitizeSlug returns a form of s safe for use in filenames: lowercase
+// ASCII letters and digits separated by single hyphens. Accented letters
+// are transliterated to their base letters, so "Crème Brûlée" becomes
+// "creme-brulee", and other characters such as emoji are treated as
+// separators.
+func SanitizeSlug(s string) string {
+	return strings.Join(slugWords(s), "-")
+}
+
+// transliterations spells letters that do not decompose into an ASCII
+// base letter and combining marks.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// slugWords returns the lowercase ASCII words of s.
+func slugWords(s string) []string {
+	var b strings.Builder
+	for _, c := range norm.NFKD.String(strings.ToLower(s)) {
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+			b.WriteRune(c)
+		case unicode.Is(unicode.Mn, c):
+			// Drop the combining marks left by decomposing accented
+			// letters.
+		case transliterations[c] != "":
+			b.WriteString(transliterations[c])
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// fileName returns the base name used for files derived from the
+// recipe, falling back to its sanitized name and then its ID when the
+// slug has no usable characters.
+func (r Recipe) fileName() string {
+	if s := r.CleanSlug(); s != "" {
+		return s
+	}
+	if s := SanitizeSlug(r.Name); s != "" {
+		return s
+	}
+	return r.ID
+}
+
+// isSlugID reports whether w looks like an ID appended to a slug: a
+// number, or a hexadecimal string such as a Hello Fresh recipe ID.
+func isSlugID(w string) bool {
+	hasDigit := false
+	for _, c := range w {
+		switch {
+		case '0' <= c && c <= '9':
+			hasDigit = true
+		case 'a' <= c && c <= 'f':
+		default:
+			return false
+		}
+	}
+	return hasDigit && (len(w) >= 8 || strings.Trim(w, "0123456789") == "")
+}
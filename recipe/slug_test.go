@@ -0,0 +1,55 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import "testing"
+
+func TestCleanSlug(t *testing.T) {
+	tests := []struct {
+		slug, want string
+	}{
+		{"", ""},
+		{"spicy-tacos", "spicy-tacos"},
+		{"Spicy-Tacos", "spicy-tacos"},
+		{"spicy-tacos-5f4d2a1b3c9e8d7f6a5b4c3d", "spicy-tacos"},
+		{"spicy-tacos-123", "spicy-tacos"},
+		{"spicy-tacos-5f4d2a1b-42", "spicy-tacos"},
+		{"spicy--tacos__with_lime", "spicy-tacos-with-lime"},
+		{"--spicy tacos--", "spicy-tacos"},
+		{"beef-stew-2023-edition", "beef-stew-2023-edition"},
+		{"cafe-bean-salad", "cafe-bean-salad"},
+		// A slug that is only an ID is kept rather than emptied.
+		{"2023", "2023"},
+		{"5f4d2a1b3c9e8d7f6a5b4c3d", "5f4d2a1b3c9e8d7f6a5b4c3d"},
+	}
+	for _, tt := range tests {
+		if got := (Recipe{Slug: tt.slug}).CleanSlug(); got != tt.want {
+			t.Errorf("CleanSlug(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeSlug(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"Crème Brûlée", "creme-brulee"},
+		{"Jalapeño Poppers 🌶️🔥", "jalapeno-poppers"},
+		{"🍕 Pizza Night", "pizza-night"},
+		{"Käsespätzle & Salat", "kasespatzle-salat"},
+		{"Straße/Smørrebrød", "strasse-smorrebrod"},
+		{"Chicken: Tacos?*<>|", "chicken-tacos"},
+		{"../../etc/passwd", "etc-passwd"},
+		{"🍜🍣", ""},
+	}
+	for _, tt := range tests {
+		if got := SanitizeSlug(tt.s); got != tt.want {
+			t.Errorf("SanitizeSlug(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+	if got := (Recipe{ID: "64ab", Name: "🍜🍣"}).fileName(); got != "64ab" {
+		t.Errorf("fileName() of an emoji-only name = %q, want the ID", got)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"sort"
+	"strings"
+)
+
+// SortByName sorts the recipes alphabetically by name, ignoring case.
+func (rs Recipes) SortByName() {
+	sort.SliceStable(rs, func(i, j int) bool {
+		return strings.ToLower(rs[i].Name) < strings.ToLower(rs[j].Name)
+	})
+}
+
+// Head returns the first n recipes, or all recipes if there are fewer
+// than n.
+func (rs Recipes) Head(n int) Recipes {
+	if n < len(rs) {
+		return rs[:n]
+	}
+	return rs
+}
+
+// SortByIngredientCount sorts the recipes by number of ingredients,
+// fewest first. Recipes with the same number of ingredients are sorted
+// by name.
+func (rs Recipes) SortByIngredientCount() {
+	sort.SliceStable(rs, func(i, j int) bool {
+		if len(rs[i].Ingredients) != len(rs[j].Ingredients) {
+			return len(rs[i].Ingredients) < len(rs[j].Ingredients)
+		}
+		return strings.ToLower(rs[i].Name) < strings.ToLower(rs[j].Name)
+	})
+}
+
+// SortByProteinRatio sorts the recipes by protein-to-calorie ratio,
+// highest first. Recipes missing protein or calorie data sort last.
+func (rs Recipes) SortByProteinRatio() {
+	type keyed struct {
+		r     Recipe
+		ratio float64
+		ok    bool
+	}
+	ks := make([]keyed, len(rs))
+	for i, r := range rs {
+		ratio, err := r.ProteinCalorieRatio()
+		ks[i] = keyed{r, ratio, err == nil}
+	}
+	sort.SliceStable(ks, func(i, j int) bool {
+		if ks[i].ok != ks[j].ok {
+			return ks[i].ok
+		}
+		return ks[i].ratio > ks[j].ratio
+	})
+	for i, k := range ks {
+		rs[i] = k.r
+	}
+}
+
+// SortByAmount sorts the yield ingredients by amount, largest first.
+// Amounts are compared in grams where their units can be converted.
+func (y Yield) SortByAmount() {
+	sort.SliceStable(y.Ingredients, func(i, j int) bool {
+		return y.Ingredients[i].sortAmount() > y.Ingredients[j].sortAmount()
+	})
+}
+
+func (iy IngredientYield) sortAmount() float64 {
+	if g, ok := iy.Grams(); ok {
+		return g
+	}
+	return iy.Amount
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Matthew Dargan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package recipe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHead(t *testing.T) {
+	rs := Recipes{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, []string{}},
+		{2, []string{"a", "b"}},
+		{3, []string{"a", "b", "c"}},
+		{10, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		if got := ids(rs.Head(tt.n)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Head(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSortByIngredientCount(t *testing.T) {
+	ingreds := func(n int) []Ingredient { return make([]Ingredient, n) }
+	rs := Recipes{
+		{ID: "three", Name: "Stew", Ingredients: ingreds(3)},
+		{ID: "one-b", Name: "omelette", Ingredients: ingreds(1)},
+		{ID: "two", Name: "Salad", Ingredients: ingreds(2)},
+		{ID: "one-a", Name: "Avocado Toast", Ingredients: ingreds(1)},
+	}
+	rs.SortByIngredientCount()
+	want := []string{"one-a", "one-b", "two", "three"}
+	if got := ids(rs); !reflect.DeepEqual(got, want) {
+		t.Errorf("SortByIngredientCount() order = %v, want %v", got, want)
+	}
+}
+
+func TestSortByAmount(t *testing.T) {
+	y := Yield{Ingredients: []IngredientYield{
+		{ID: "butter", Amount: 2, Unit: "tbsp"},
+		{ID: "flour", Amount: 0.5, Unit: "kg"},
+		{ID: "milk", Amount: 250, Unit: "ml"},
+		{ID: "eggs", Amount: 3, Unit: "piece"},
+		{ID: "sugar", Amount: 1, Unit: "cup"},
+		{ID: "salt", Amount: 5, Unit: "g"},
+	}}
+	y.SortByAmount()
+	var got []string
+	for _, iy := range y.Ingredients {
+		got = append(got, iy.ID)
+	}
+	want := []string{"flour", "milk", "sugar", "butter", "salt", "eggs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortByAmount() order = %v, want %v", got, want)
+	}
+}
+
+func TestSortByProteinRatio(t *testing.T) {
+	nutrition := func(protein float64, energy float64, unit string) []Nutrition {
+		return []Nutrition{{Name: "Energy", Amount: energy, Unit: unit}, {Name: "Protein", Amount: protein, Unit: "g"}}
+	}
+	rs := Recipes{
+		{ID: "no-protein", Nutrition: []Nutrition{{Name: "Energy", Amount: 500, Unit: "kcal"}}},
+		{ID: "pasta", Nutrition: nutrition(20, 800, "kcal")},
+		{ID: "no-data"},
+		{ID: "chicken", Nutrition: nutrition(40, 400, "kcal")},
+		{ID: "stew", Nutrition: nutrition(30, 2092, "kJ")},
+	}
+	rs.SortByProteinRatio()
+	want := []string{"chicken", "stew", "pasta", "no-protein", "no-data"}
+	if got := ids(rs); !reflect.DeepEqual(got, want) {
+		t.Errorf("SortByProteinRatio() order = %v, want %v", got, want)
+	}
+}